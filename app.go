@@ -2,118 +2,100 @@ package main
 
 import (
     "context"
-    "encoding/json"
     "fmt"
-    "os"
     "time"
-    "golang.org/x/sys/windows/registry"
-    "path/filepath"
+
+    "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
-	ctx context.Context
+	ctx      context.Context
+	watchdog *Watchdog
+	// loginSem 同一时间只执行一个登录或注销，等待时可随 ctx 取消
+	loginSem chan struct{}
+	// browsers 登录、连接测试与网络检测共用的浏览器，通过 ops 传给各登录驱动，退出时关闭
+	browsers *BrowserService
+	// ops 登录、注销与网络检测的父 context，程序退出时取消，进行中的浏览器操作随之中止
+	ops       context.Context
+	cancelOps context.CancelFunc
+	// operations 进行中的操作，可按 ID 取消
+	operations *OperationRegistry
+	// steps 登录步骤事件的订阅者
+	steps stepSubscribers
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	browsers := NewBrowserService(defaultBrowserIdleTimeout)
+	ops, cancel := context.WithCancel(WithBrowserService(context.Background(), browsers))
+	return &App{
+		browsers:   browsers,
+		ops:        ops,
+		cancelOps:  cancel,
+		operations: NewOperationRegistry(),
+		loginSem:   make(chan struct{}, 1),
+	}
 }
 
-// startup is called when the app starts. The context is saved
-// so we can call the runtime methods
-func (a *App) startup(ctx context.Context) {
-	a.ctx = ctx
+// acquireLogin 等待进行中的登录或注销结束，ctx 结束时放弃等待，返回释放函数
+func (a *App) acquireLogin(ctx context.Context) (func(), error) {
+	select {
+	case a.loginSem <- struct{}{}:
+		return func() { <-a.loginSem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
-
-func (a *App) EnableAutoStart() error {
-    exePath, err := os.Executable()
-    if err != nil {
-        return fmt.Errorf("failed to get executable path: %w", err)
-    }
-
-    fmt.Println(exePath)
-
-    runKey, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.SET_VALUE)
-    if err != nil {
-        return fmt.Errorf("failed to open registry key: %w", err)
-    }
-    defer runKey.Close()
-
-    err = runKey.SetStringValue("YzuAutologin", exePath)
-    if err != nil {
-        return fmt.Errorf("failed to set registry value: %w", err)
-    }
-
-    return nil
+// emit 向前端发送事件，命令行模式下没有界面时忽略
+func (a *App) emit(name string, data ...interface{}) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, name, data...)
 }
 
-func (a *App) DisableAutoStart() error {
-    runKey, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Run`, registry.SET_VALUE)
-    if err != nil {
-        return fmt.Errorf("failed to open registry key: %w", err)
-    }
-    defer runKey.Close()
+// startup is called when the app starts. The context is saved
+// so we can call the runtime methods
+func (a *App) startup(ctx context.Context) {
+	a.ctx = ctx
 
-    err = runKey.DeleteValue("YzuAutologin")
-    if err != nil {
-        return fmt.Errorf("failed to delete registry value: %w", err)
-    }
+	if config, err := LoadConfigOrDefault(); err == nil {
+		a.browsers.SetIdleTimeout(config.browserIdleTimeout())
+	}
 
-    return nil
+	a.watchdog = NewWatchdog(watchdogInterval, a.watchdogLogin)
+	a.watchdog.Start()
 }
 
-
-// SaveValue saves the given value to a JSON file
-func (a *App) SaveValue(data map[string]string) error {
-    file, err := os.Create("data.json")
-    if err != nil {
-        return err
-    }
-    defer file.Close()
-
-    encoder := json.NewEncoder(file)
-    if err := encoder.Encode(data); err != nil {
-        return err
-    }
-
-    return nil
+// shutdown is called when the app is closing
+func (a *App) shutdown(ctx context.Context) {
+	// 先中止进行中的操作，网络守护才能尽快退出
+	a.cancelOps()
+	if a.watchdog != nil {
+		a.watchdog.Stop()
+	}
+	a.browsers.Close()
 }
 
-// ReadData reads the data from data.json file
-func (a *App) ReadData() (map[string]string, error) {
-    exePath, err := os.Executable()
-    if (err != nil) {
-        return nil, fmt.Errorf("failed to get executable path: %w", err)
-    }
-    exeDir := filepath.Dir(exePath)
-    fmt.Println("Executable directory:", exeDir)
-    filename := filepath.Join(exeDir, "data.json")
-    file, err := os.Open(filename)
-    if err != nil {
-        return nil, err
-    }
-    defer file.Close()
-
-    data := make(map[string]string)
-    decoder := json.NewDecoder(file)
-    if err := decoder.Decode(&data); err != nil {
-        return nil, err
-    }
-
-    return data, nil
-}
 
 // DetectNetworkLoginPage 自动检测校园网登录页面
 func (a *App) DetectNetworkLoginPage() (string, error) {
-	detector, err := NewNetworkDetector(30 * time.Second)
+	ctx, done := a.track(OperationDetect)
+	defer done()
+	return detectLoginPage(ctx)
+}
+
+// detectLoginPage 使用共享浏览器检测校园网登录页面
+func detectLoginPage(ctx context.Context) (string, error) {
+	detector, err := NewNetworkDetector(ctx, 30*time.Second)
 	if err != nil {
 		return "", fmt.Errorf("创建网络检测器失败: %w", err)
 	}
 	defer detector.Close()
 
-	loginURL, err := detector.DetectLoginPage()
+	loginURL, err := detector.DetectLoginPage(ctx)
 	if err != nil {
 		return "", fmt.Errorf("检测登录页面失败: %w", err)
 	}
@@ -123,39 +105,88 @@ func (a *App) DetectNetworkLoginPage() (string, error) {
 
 // GetNetworkStatus 获取网络状态信息
 func (a *App) GetNetworkStatus() (map[string]interface{}, error) {
-	detector, err := NewNetworkDetector(30 * time.Second)
+	ctx, done := a.track(OperationStatus)
+	defer done()
+
+	status, err := a.networkStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 识别认证系统：优先使用检测到的登录页面，已联网时使用配置中的认证地址
+	portalURL, _ := status["login_url"].(string)
+	if portalURL == "" {
+		if config, err := LoadConfigOrDefault(); err == nil {
+			portalURL = config.Webindex
+		}
+	}
+	if portalURL != "" {
+		if fingerprint, err := DetectPortal(ctx, portalURL, 10*time.Second); err == nil {
+			status["vendor"] = fingerprint.Vendor
+			status["vendor_name"] = fingerprint.Name
+			status["vendor_version"] = fingerprint.Version
+		} else {
+			status["vendor"] = VendorUnknown
+		}
+	}
+
+	return status, nil
+}
+
+// networkStatus 先通过 HTTP 请求检测连通性，结果明确时不启动浏览器，
+// 请求失败或无法判断时再使用浏览器检测
+func (a *App) networkStatus(ctx context.Context) (map[string]interface{}, error) {
+	connected, loginURL, err := CheckConnectivity(ctx, 10*time.Second)
+	if err == nil && connected {
+		return map[string]interface{}{
+			"connected":            true,
+			"connectivity_result":  "网络已连接，可以正常访问互联网",
+			"needs_authentication": false,
+		}, nil
+	}
+	if err == nil && loginURL != "" {
+		return map[string]interface{}{
+			"connected":            false,
+			"connectivity_result":  fmt.Sprintf("网络需要认证，已重定向到登录页面: %s", loginURL),
+			"login_url":            loginURL,
+			"needs_authentication": true,
+		}, nil
+	}
+
+	detector, err := NewNetworkDetector(ctx, 30*time.Second)
 	if err != nil {
 		return nil, fmt.Errorf("创建网络检测器失败: %w", err)
 	}
 	defer detector.Close()
 
-	status, err := detector.GetNetworkStatus()
+	status, err := detector.GetNetworkStatus(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("获取网络状态失败: %w", err)
 	}
-
 	return status, nil
 }
 
 // AutoDetectAndSaveLoginURL 自动检测并保存登录URL
 func (a *App) AutoDetectAndSaveLoginURL() (string, error) {
-	loginURL, err := a.DetectNetworkLoginPage()
+	ctx, done := a.track(OperationDetect)
+	defer done()
+
+	loginURL, err := detectLoginPage(ctx)
 	if err != nil {
 		return "", err
 	}
 
-	// 读取现有数据
-	data, err := a.ReadData()
+	// 读取现有配置，文件不存在时使用默认配置
+	config, err := LoadConfigOrDefault()
 	if err != nil {
-		// 如果文件不存在，创建新数据
-		data = make(map[string]string)
+		return "", err
 	}
 
 	// 更新登录URL
-	data["webindex"] = loginURL
+	config.Webindex = loginURL
 
-	// 保存数据
-	err = a.SaveValue(data)
+	// 保存配置
+	err = SaveConfig(config)
 	if err != nil {
 		return "", fmt.Errorf("保存登录URL失败: %w", err)
 	}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// autostartName 自启动项名称
+const autostartName = "YzuAutologin"
+
+// EnableAutoStart 开启开机自启动
+func (a *App) EnableAutoStart() error {
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	return enableAutoStart(exePath, autostartArgs())
+}
+
+// DisableAutoStart 关闭开机自启动
+func (a *App) DisableAutoStart() error {
+	return disableAutoStart()
+}
+
+// IsAutoStartEnabled 查询系统中是否已登记开机自启动
+func (a *App) IsAutoStartEnabled() (bool, error) {
+	return isAutoStartEnabled()
+}
+
+// autostartArgs 自启动时需要保留的启动参数，确保使用同一个配置目录
+func autostartArgs() []string {
+	var args []string
+	if configDirFlag != "" {
+		args = append(args, "--config-dir", configDirFlag)
+	}
+	if portableFlag {
+		args = append(args, "--portable")
+	}
+	return args
+}
+
+// quoteCommandLine 拼接命令行，包含空格的参数加引号
+func quoteCommandLine(exePath string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{exePath}, args...) {
+		if strings.ContainsAny(arg, " \t\"") {
+			arg = `"` + strings.ReplaceAll(arg, `"`, `\"`) + `"`
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// runKeyCommand 返回写入 Windows 注册表 Run 项的命令行，路径始终加引号，避免包含空格的路径被截断
+func runKeyCommand(exePath string, args []string) string {
+	command := quoteCommandLine(exePath, args)
+	if !strings.HasPrefix(command, `"`) {
+		command = `"` + exePath + `"` + strings.TrimPrefix(command, exePath)
+	}
+	return command
+}
+
+// desktopExecReserved Desktop Entry 规范中需要加引号的字符
+const desktopExecReserved = " \t\n\"'\\><~|&;$*?#()`"
+
+// desktopExecArg 按 Desktop Entry 规范转义 Exec 中的一个参数：% 写作 %%，
+// 包含保留字符的参数加双引号，引号内的 "、`、$、\ 前加反斜杠
+func desktopExecArg(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if arg != "" && !strings.ContainsAny(arg, desktopExecReserved) {
+		return arg
+	}
+
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for _, r := range arg {
+		if strings.ContainsRune("\"`$\\", r) {
+			quoted.WriteByte('\\')
+		}
+		quoted.WriteRune(r)
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
+
+// desktopExecLine 返回 .desktop 文件中 Exec 键的值。
+// 值本身还要按字符串类型转义，因此引号内的反斜杠最终写作四个
+func desktopExecLine(exePath string, args []string) string {
+	parts := make([]string, 0, len(args)+1)
+	for _, arg := range append([]string{exePath}, args...) {
+		parts = append(parts, desktopExecArg(arg))
+	}
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(strings.Join(parts, " "))
+}
+
+// desktopEntry 生成 XDG 自启动目录中的 .desktop 文件内容
+func desktopEntry(exePath string, args []string) string {
+	return fmt.Sprintf(`[Desktop Entry]
+Type=Application
+Name=%s
+Comment=扬州大学校园网自动登录
+Exec=%s
+Terminal=false
+X-GNOME-Autostart-enabled=true
+`, autostartName, desktopExecLine(exePath, args))
+}
+
+// launchAgentLabel LaunchAgent 的标识
+const launchAgentLabel = "cn.edu.yzu.autologin"
+
+// launchAgentPlist 生成 macOS LaunchAgent 的 plist 内容，参数逐个写入 ProgramArguments，不经过 shell
+func launchAgentPlist(exePath string, args []string) string {
+	var programArgs bytes.Buffer
+	for _, arg := range append([]string{exePath}, args...) {
+		programArgs.WriteString("\t\t<string>")
+		xml.EscapeText(&programArgs, []byte(arg))
+		programArgs.WriteString("</string>\n")
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Label</key>
+	<string>%s</string>
+	<key>ProgramArguments</key>
+	<array>
+%s	</array>
+	<key>RunAtLoad</key>
+	<true/>
+</dict>
+</plist>
+`, launchAgentLabel, programArgs.String())
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// autostartDesktopFile 返回 XDG 自启动目录中的 .desktop 文件路径
+func autostartDesktopFile() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(configDir, "autostart", "yzuautologin.desktop"), nil
+}
+
+func enableAutoStart(exePath string, args []string) error {
+	path, err := autostartDesktopFile()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create autostart directory: %w", err)
+	}
+
+	content := desktopEntry(exePath, args)
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("failed to write autostart file: %w", err)
+	}
+
+	return nil
+}
+
+func disableAutoStart() error {
+	path, err := autostartDesktopFile()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove autostart file: %w", err)
+	}
+
+	return nil
+}
+
+func isAutoStartEnabled() (bool, error) {
+	path, err := autostartDesktopFile()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// autostartTestPath 包含空格与需要转义字符的可执行文件路径
+const autostartTestPath = "/home/a b/it's \"x\"/$HOME/`id`/100%/back\\slash/YzuAutologin"
+
+func TestDesktopEntry(t *testing.T) {
+	content := desktopEntry(autostartTestPath, []string{"--config-dir", "/tmp/cfg dir", "--portable"})
+
+	// 引号内的 "、`、$ 前加反斜杠，反斜杠本身再按字符串类型转义；% 写作 %%
+	want := `Exec="/home/a b/it's \\"x\\"/\\$HOME/\\` + "`" + `id\\` + "`" +
+		`/100%%/back\\\\slash/YzuAutologin" --config-dir "/tmp/cfg dir" --portable`
+	var exec string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "Exec=") {
+			exec = line
+		}
+	}
+	if exec != want {
+		t.Errorf("Exec 行为\n%s\n期望\n%s", exec, want)
+	}
+	if !strings.HasPrefix(content, "[Desktop Entry]\n") || !strings.Contains(content, "Name="+autostartName+"\n") {
+		t.Errorf(".desktop 文件内容不正确:\n%s", content)
+	}
+}
+
+func TestDesktopExecArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"/usr/bin/YzuAutologin", "/usr/bin/YzuAutologin"},
+		{"--portable", "--portable"},
+		{"50%", "50%%"},
+		{"", `""`},
+		{"a b", `"a b"`},
+		{"a;b", `"a;b"`},
+		{`say "hi"`, `"say \"hi\""`},
+	}
+
+	for _, tt := range tests {
+		if got := desktopExecArg(tt.arg); got != tt.want {
+			t.Errorf("desktopExecArg(%q) = %s，期望 %s", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestLaunchAgentPlist(t *testing.T) {
+	args := []string{"--config-dir", "/tmp/<cfg> & dir"}
+	content := launchAgentPlist(autostartTestPath, args)
+
+	var plist struct {
+		Dict struct {
+			Keys   []string `xml:"key"`
+			Label  string   `xml:"string"`
+			Values []string `xml:"array>string"`
+		} `xml:"dict"`
+	}
+	if err := xml.Unmarshal([]byte(content), &plist); err != nil {
+		t.Fatalf("plist 不是有效的 XML: %v\n%s", err, content)
+	}
+	if plist.Dict.Label != launchAgentLabel {
+		t.Errorf("Label 为 %q", plist.Dict.Label)
+	}
+	want := append([]string{autostartTestPath}, args...)
+	if !reflect.DeepEqual(plist.Dict.Values, want) {
+		t.Errorf("ProgramArguments 为 %q，期望 %q", plist.Dict.Values, want)
+	}
+}
+
+func TestRunKeyCommand(t *testing.T) {
+	tests := []struct {
+		exePath string
+		args    []string
+		want    string
+	}{
+		{`C:\Apps\YzuAutologin.exe`, nil, `"C:\Apps\YzuAutologin.exe"`},
+		{`C:\Program Files\YzuAutologin.exe`, []string{"--portable"}, `"C:\Program Files\YzuAutologin.exe" --portable`},
+		{`C:\Apps\YzuAutologin.exe`, []string{"--config-dir", `D:\my config`}, `"C:\Apps\YzuAutologin.exe" --config-dir "D:\my config"`},
+	}
+
+	for _, tt := range tests {
+		if got := runKeyCommand(tt.exePath, tt.args); got != tt.want {
+			t.Errorf("runKeyCommand(%q, %q) = %s，期望 %s", tt.exePath, tt.args, got, tt.want)
+		}
+	}
+}
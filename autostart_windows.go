@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// runKeyPath 当前用户的开机启动注册表项
+const runKeyPath = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+func enableAutoStart(exePath string, args []string) error {
+	runKey, err := registry.OpenKey(registry.CURRENT_USER, runKeyPath, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer runKey.Close()
+
+	err = runKey.SetStringValue(autostartName, runKeyCommand(exePath, args))
+	if err != nil {
+		return fmt.Errorf("failed to set registry value: %w", err)
+	}
+
+	return nil
+}
+
+func disableAutoStart() error {
+	runKey, err := registry.OpenKey(registry.CURRENT_USER, runKeyPath, registry.SET_VALUE)
+	if err != nil {
+		return fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer runKey.Close()
+
+	err = runKey.DeleteValue(autostartName)
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return fmt.Errorf("failed to delete registry value: %w", err)
+	}
+
+	return nil
+}
+
+func isAutoStartEnabled() (bool, error) {
+	runKey, err := registry.OpenKey(registry.CURRENT_USER, runKeyPath, registry.QUERY_VALUE)
+	if err != nil {
+		return false, fmt.Errorf("failed to open registry key: %w", err)
+	}
+	defer runKey.Close()
+
+	_, _, err = runKey.GetStringValue(autostartName)
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read registry value: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// 浏览器来源
+const (
+	BrowserSourceConfigured = "configured"
+	BrowserSourceBundled    = "bundled"
+	BrowserSourceSystem     = "system"
+)
+
+// browserBundleDirName 离线附带浏览器的目录名，位于程序目录或配置目录下
+const browserBundleDirName = "browser"
+
+// browserBundleDepth 在附带浏览器目录中查找可执行文件的最大层数，
+// 兼容 chrome-win/chrome.exe 与 Chromium.app/Contents/MacOS/Chromium 这样的解压结构
+const browserBundleDepth = 4
+
+// ErrBrowserNotFound 找不到可用的浏览器
+var ErrBrowserNotFound = errors.New("未找到可用的浏览器")
+
+// BrowserInfo 解析出的浏览器
+type BrowserInfo struct {
+	Path   string `json:"path"`
+	Source string `json:"source"`
+}
+
+// browserExecutables 附带浏览器目录中可识别的可执行文件名
+var browserExecutables = map[string][]string{
+	"windows": {"msedge.exe", "chrome.exe"},
+	"darwin":  {"Microsoft Edge", "Google Chrome", "Chromium"},
+	"linux":   {"msedge", "microsoft-edge", "chrome", "google-chrome", "chromium", "chromium-browser"},
+}
+
+// systemBrowsers 各平台已安装浏览器的位置，按 Edge、Chrome、Chromium 的顺序查找；
+// 不含路径分隔符的项在 PATH 中查找
+func systemBrowsers() []string {
+	switch runtime.GOOS {
+	case "windows":
+		var paths []string
+		for _, env := range []string{"ProgramFiles(x86)", "ProgramFiles", "LocalAppData"} {
+			dir := os.Getenv(env)
+			if dir == "" {
+				continue
+			}
+			for _, rel := range []string{
+				`Microsoft\Edge\Application\msedge.exe`,
+				`Google\Chrome\Application\chrome.exe`,
+				`Chromium\Application\chrome.exe`,
+			} {
+				paths = append(paths, filepath.Join(dir, rel))
+			}
+		}
+		return append(paths, "msedge.exe", "chrome.exe")
+	case "darwin":
+		var paths []string
+		home, _ := os.UserHomeDir()
+		for _, dir := range []string{"/Applications", filepath.Join(home, "Applications")} {
+			paths = append(paths,
+				filepath.Join(dir, "Microsoft Edge.app/Contents/MacOS/Microsoft Edge"),
+				filepath.Join(dir, "Google Chrome.app/Contents/MacOS/Google Chrome"),
+				filepath.Join(dir, "Chromium.app/Contents/MacOS/Chromium"),
+			)
+		}
+		return paths
+	default:
+		return []string{
+			"microsoft-edge", "microsoft-edge-stable",
+			"google-chrome", "google-chrome-stable", "chrome",
+			"chromium", "chromium-browser", "/snap/bin/chromium",
+		}
+	}
+}
+
+// browserBundleDirs 可能放置离线浏览器的目录：程序目录优先，便于随程序一起分发
+func browserBundleDirs() []string {
+	dirs := []string{filepath.Join(executableDir(), browserBundleDirName)}
+	if dir := filepath.Join(ConfigDir(), browserBundleDirName); !sameDir(dir, dirs[0]) {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// ResolveBrowser 按配置的路径、附带的浏览器、系统已安装的浏览器的顺序查找浏览器。
+// 不会自动下载 Chromium：连接认证网络前通常无法访问互联网，下载只会卡住
+func ResolveBrowser(configured string) (*BrowserInfo, error) {
+	if configured != "" {
+		path, err := browserExecutable(configured)
+		if err != nil {
+			return nil, fmt.Errorf("配置的浏览器 browser_path 不可用: %w", err)
+		}
+		return &BrowserInfo{Path: path, Source: BrowserSourceConfigured}, nil
+	}
+
+	for _, dir := range browserBundleDirs() {
+		if path := findBundledBrowser(dir, browserBundleDepth); path != "" {
+			return &BrowserInfo{Path: path, Source: BrowserSourceBundled}, nil
+		}
+	}
+
+	for _, candidate := range systemBrowsers() {
+		if path, err := browserExecutable(candidate); err == nil {
+			return &BrowserInfo{Path: path, Source: BrowserSourceSystem}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w。请安装 Microsoft Edge、Google Chrome 或 Chromium，"+
+		"或用 config set browser_path <路径> 指定浏览器，或将离线浏览器解压到 %s；"+
+		"也可以改用 http、srun、form 等无需浏览器的登录驱动", ErrBrowserNotFound, strings.Join(browserBundleDirs(), " 或 "))
+}
+
+// browserExecutable 检查路径是否为可执行文件，不含路径分隔符时在 PATH 中查找
+func browserExecutable(path string) (string, error) {
+	if !strings.ContainsAny(path, `/\`) {
+		return exec.LookPath(path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s 是目录，请指定浏览器的可执行文件", path)
+	}
+	return path, nil
+}
+
+// findBundledBrowser 在目录中逐层查找浏览器可执行文件，找不到时返回空字符串
+func findBundledBrowser(dir string, depth int) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	for _, name := range browserExecutables[runtime.GOOS] {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+
+	if depth <= 1 {
+		return ""
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if path := findBundledBrowser(filepath.Join(dir, entry.Name()), depth-1); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// configuredBrowser 读取配置中的浏览器路径并解析浏览器
+func configuredBrowser() (*BrowserInfo, error) {
+	path := ""
+	if config, err := LoadConfigOrDefault(); err == nil {
+		path = config.BrowserPath
+	} else {
+		log.Printf("读取配置失败，按默认顺序查找浏览器: %v", err)
+	}
+	return ResolveBrowser(path)
+}
+
+// GetBrowser 返回浏览器模拟登录将使用的浏览器，找不到时返回说明如何解决的错误
+func (a *App) GetBrowser() (*BrowserInfo, error) {
+	return configuredBrowser()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestResolveBrowser(t *testing.T) {
+	dir := t.TempDir()
+	configured := filepath.Join(dir, "my-chrome")
+	if err := os.WriteFile(configured, []byte{}, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := ResolveBrowser(configured)
+	if err != nil || info.Path != configured || info.Source != BrowserSourceConfigured {
+		t.Errorf("配置的浏览器没有优先使用: %+v %v", info, err)
+	}
+
+	// 配置的路径不可用时直接报错，不再查找其他浏览器
+	if _, err := ResolveBrowser(filepath.Join(dir, "missing")); err == nil {
+		t.Error("配置的浏览器不存在时应返回错误")
+	}
+	if _, err := ResolveBrowser(dir); err == nil {
+		t.Error("配置的路径为目录时应返回错误")
+	}
+
+	// 配置目录下附带的浏览器优先于系统安装的浏览器
+	bundle := filepath.Join(ConfigDir(), browserBundleDirName)
+	t.Cleanup(func() { os.RemoveAll(bundle) })
+	name := browserExecutables[runtime.GOOS][0]
+	bundled := filepath.Join(bundle, "chrome-unpacked", name)
+	if err := os.MkdirAll(filepath.Dir(bundled), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bundled, []byte{}, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err = ResolveBrowser("")
+	if err != nil || info.Path != bundled || info.Source != BrowserSourceBundled {
+		t.Errorf("没有找到附带的浏览器: %+v %v", info, err)
+	}
+}
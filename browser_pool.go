@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+const (
+	// defaultBrowserIdleTimeout 配置未指定时浏览器空闲多久后关闭
+	defaultBrowserIdleTimeout = 5 * time.Minute
+	// browserHealthTimeout 复用浏览器前检查其是否仍在运行的超时
+	browserHealthTimeout = 2 * time.Second
+	// maxBrowserReapInterval 检查空闲浏览器的最长间隔
+	maxBrowserReapInterval = 30 * time.Second
+)
+
+// pooledBrowser 一个已启动的浏览器进程
+type pooledBrowser struct {
+	launcher *launcher.Launcher
+	browser  *rod.Browser
+	headless bool
+	leases   int
+	lastUsed time.Time
+}
+
+// close 断开连接并结束浏览器进程
+func (b *pooledBrowser) close() {
+	if b.browser != nil {
+		if err := b.browser.Close(); err != nil {
+			log.Printf("关闭浏览器时出错: %v", err)
+		}
+	}
+	if b.launcher != nil {
+		b.launcher.Kill()
+	}
+}
+
+// alive 检查浏览器是否仍能响应
+func (b *pooledBrowser) alive() bool {
+	_, err := proto.BrowserGetVersion{}.Call(b.browser.Timeout(browserHealthTimeout))
+	return err == nil
+}
+
+// browserLaunch 正在进行的浏览器启动，done 在启动结束时关闭
+type browserLaunch struct {
+	done chan struct{}
+}
+
+// BrowserService 由 App 持有的共享浏览器：首次使用时启动，通过租约共享，
+// 崩溃后在下次租用时重新启动，空闲超时后关闭，程序退出时统一关闭。
+// 有界面与无界面的浏览器各保留一个
+type BrowserService struct {
+	mu        sync.Mutex
+	instances map[bool]*pooledBrowser
+	// launching 正在启动的浏览器，启动与健康检查都不持有锁，其他租用者等待启动结束
+	launching   map[bool]*browserLaunch
+	idleTimeout time.Duration
+	// reaping 空闲检查是否在运行，没有浏览器时退出，避免常驻协程
+	reaping bool
+	stop    chan struct{}
+}
+
+// NewBrowserService 创建浏览器服务，此时不启动浏览器
+func NewBrowserService(idleTimeout time.Duration) *BrowserService {
+	s := &BrowserService{instances: map[bool]*pooledBrowser{}, launching: map[bool]*browserLaunch{}}
+	s.SetIdleTimeout(idleTimeout)
+	return s
+}
+
+// browserServiceKey 在 context 中保存浏览器服务的键
+type browserServiceKey struct{}
+
+// errNoBrowserService context 中没有浏览器服务
+var errNoBrowserService = errors.New("没有可用的浏览器服务")
+
+// WithBrowserService 返回携带浏览器服务的 context，浏览器登录驱动与网络检测器从中租用浏览器
+func WithBrowserService(ctx context.Context, service *BrowserService) context.Context {
+	return context.WithValue(ctx, browserServiceKey{}, service)
+}
+
+// browserServiceFrom 返回 context 中的浏览器服务
+func browserServiceFrom(ctx context.Context) (*BrowserService, error) {
+	if service, ok := ctx.Value(browserServiceKey{}).(*BrowserService); ok {
+		return service, nil
+	}
+	return nil, errNoBrowserService
+}
+
+// SetIdleTimeout 修改空闲超时，不大于 0 时使用默认值
+func (s *BrowserService) SetIdleTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultBrowserIdleTimeout
+	}
+	s.mu.Lock()
+	s.idleTimeout = timeout
+	s.mu.Unlock()
+}
+
+// BrowserLease 一次浏览器租约，期间打开的页面在归还时关闭。
+// 租约绑定创建时的 context，context 结束时自动归还
+type BrowserLease struct {
+	service  *BrowserService
+	instance *pooledBrowser
+	ctx      context.Context
+
+	mu       sync.Mutex
+	pages    []*rod.Page
+	released bool
+	stop     func() bool
+}
+
+// Acquire 租用浏览器，浏览器未启动或已崩溃时先启动，使用完毕后调用 Release。
+// 其他租用者正在启动同一种浏览器时等待其启动结束，不会重复启动
+func (s *BrowserService) Acquire(ctx context.Context, headless bool) (*BrowserLease, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		s.mu.Lock()
+		if launch := s.launching[headless]; launch != nil {
+			s.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-launch.done:
+			}
+			continue
+		}
+
+		if instance := s.instances[headless]; instance != nil {
+			// 先占用租约，健康检查期间不会被空闲检查关闭
+			instance.leases++
+			s.mu.Unlock()
+			if instance.alive() {
+				return s.lease(ctx, instance), nil
+			}
+
+			log.Println("浏览器已退出，重新启动...")
+			s.mu.Lock()
+			instance.leases--
+			if s.instances[headless] == instance {
+				delete(s.instances, headless)
+				go instance.close()
+			}
+			s.mu.Unlock()
+			continue
+		}
+
+		launch := &browserLaunch{done: make(chan struct{})}
+		s.launching[headless] = launch
+		s.mu.Unlock()
+
+		instance, err := launchBrowser(ctx, headless)
+
+		s.mu.Lock()
+		delete(s.launching, headless)
+		if err == nil {
+			instance.leases++
+			s.instances[headless] = instance
+			s.startReaper()
+		}
+		s.mu.Unlock()
+		close(launch.done)
+
+		if err != nil {
+			return nil, err
+		}
+		return s.lease(ctx, instance), nil
+	}
+}
+
+// lease 为已占用的浏览器创建租约
+func (s *BrowserService) lease(ctx context.Context, instance *pooledBrowser) *BrowserLease {
+	s.mu.Lock()
+	instance.lastUsed = time.Now()
+	s.mu.Unlock()
+
+	lease := &BrowserLease{service: s, instance: instance, ctx: ctx}
+	lease.stop = context.AfterFunc(ctx, lease.Release)
+	return lease
+}
+
+// launchBrowser 启动浏览器并建立连接，启动过程可通过 ctx 取消
+func launchBrowser(ctx context.Context, headless bool) (*pooledBrowser, error) {
+	info, err := configuredBrowser()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("正在启动浏览器: %s (%s)", info.Path, info.Source)
+	// 指定可执行文件后 launcher 不会再尝试下载 Chromium
+	l := launcher.New().Context(ctx).Bin(info.Path).Headless(headless).Set("no-proxy-server")
+	controlURL, err := l.Launch()
+	if err != nil {
+		l.Kill()
+		return nil, fmt.Errorf("browser launch failed: %w", err)
+	}
+
+	browser := rod.New().ControlURL(controlURL)
+	if err := browser.Connect(); err != nil {
+		l.Kill()
+		return nil, fmt.Errorf("browser connection failed: %w", err)
+	}
+
+	return &pooledBrowser{launcher: l, browser: browser, headless: headless, lastUsed: time.Now()}, nil
+}
+
+// Browser 返回绑定租约 context 的浏览器，context 结束后其上的操作随之中止
+func (l *BrowserLease) Browser() *rod.Browser {
+	return l.instance.browser.Context(l.ctx)
+}
+
+// Page 打开页面，页面在归还租约时关闭
+func (l *BrowserLease) Page(targetURL string) (*rod.Page, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.released {
+		return nil, fmt.Errorf("浏览器租约已归还")
+	}
+
+	page, err := l.Browser().Page(proto.TargetCreateTarget{URL: targetURL})
+	if err != nil {
+		return nil, fmt.Errorf("page creation failed: %w", err)
+	}
+	l.pages = append(l.pages, page)
+	return page, nil
+}
+
+// Release 关闭租约期间打开的页面并归还浏览器，可重复调用
+func (l *BrowserLease) Release() {
+	l.mu.Lock()
+	if l.released {
+		l.mu.Unlock()
+		return
+	}
+	l.released = true
+	pages := l.pages
+	l.pages = nil
+	l.mu.Unlock()
+
+	if l.stop != nil {
+		l.stop()
+	}
+	stuck := false
+	for _, page := range pages {
+		// 租约的 context 可能已经结束，关闭页面不能再使用它
+		err := page.Context(context.Background()).Timeout(browserHealthTimeout).Close()
+		if err != nil {
+			log.Printf("关闭页面时出错: %v", err)
+			stuck = stuck || errors.Is(err, context.DeadlineExceeded)
+		}
+	}
+
+	l.service.mu.Lock()
+	l.instance.leases--
+	l.instance.lastUsed = time.Now()
+	if stuck {
+		// 页面卡住时连关闭都没有响应，直接结束浏览器，下次租用时重新启动
+		l.service.discard(l.instance)
+	}
+	l.service.mu.Unlock()
+}
+
+// discard 移除并结束无响应的浏览器，需持有锁
+func (s *BrowserService) discard(instance *pooledBrowser) {
+	if s.instances[instance.headless] == instance {
+		delete(s.instances, instance.headless)
+	}
+	log.Println("浏览器无响应，已结束")
+	go instance.close()
+}
+
+// startReaper 启动空闲检查，需持有锁
+func (s *BrowserService) startReaper() {
+	if s.reaping {
+		return
+	}
+	s.reaping = true
+	s.stop = make(chan struct{})
+	go s.reapLoop(s.stop)
+}
+
+// reapLoop 定期关闭空闲的浏览器，所有浏览器都关闭后退出
+func (s *BrowserService) reapLoop(stop chan struct{}) {
+	for {
+		s.mu.Lock()
+		interval := s.idleTimeout / 2
+		s.mu.Unlock()
+		if interval > maxBrowserReapInterval {
+			interval = maxBrowserReapInterval
+		}
+
+		select {
+		case <-stop:
+			return
+		case now := <-time.After(interval):
+			if !s.reapIdle(now) {
+				return
+			}
+		}
+	}
+}
+
+// reapIdle 关闭空闲超时的浏览器，返回是否还有浏览器在运行
+func (s *BrowserService) reapIdle(now time.Time) bool {
+	s.mu.Lock()
+	var idle []*pooledBrowser
+	for headless, instance := range s.instances {
+		if instance.leases == 0 && now.Sub(instance.lastUsed) >= s.idleTimeout {
+			idle = append(idle, instance)
+			delete(s.instances, headless)
+		}
+	}
+	running := len(s.instances) > 0
+	if !running {
+		s.reaping = false
+	}
+	s.mu.Unlock()
+
+	for _, instance := range idle {
+		log.Printf("浏览器空闲超过 %v，已关闭", s.idleTimeout)
+		instance.close()
+	}
+	return running
+}
+
+// Close 关闭所有浏览器，之后再次租用时重新启动
+func (s *BrowserService) Close() {
+	s.mu.Lock()
+	instances := s.instances
+	s.instances = map[bool]*pooledBrowser{}
+	if s.reaping {
+		close(s.stop)
+		s.reaping = false
+	}
+	s.mu.Unlock()
+
+	for _, instance := range instances {
+		log.Println("清理浏览器资源...")
+		instance.close()
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBrowserServiceReapIdle(t *testing.T) {
+	service := NewBrowserService(time.Minute)
+	now := time.Now()
+	busy := &pooledBrowser{headless: false, leases: 1, lastUsed: now.Add(-time.Hour)}
+	idle := &pooledBrowser{headless: true, lastUsed: now.Add(-2 * time.Minute)}
+	service.instances[false] = busy
+	service.instances[true] = idle
+
+	if running := service.reapIdle(now); !running {
+		t.Error("仍有租用中的浏览器时不应停止空闲检查")
+	}
+	if service.instances[true] != nil {
+		t.Error("空闲超时的浏览器没有关闭")
+	}
+	if service.instances[false] != busy {
+		t.Error("租用中的浏览器不应关闭")
+	}
+
+	busy.leases = 0
+	busy.lastUsed = now
+	if running := service.reapIdle(now.Add(30 * time.Second)); !running {
+		t.Error("未到空闲超时的浏览器不应关闭")
+	}
+	if running := service.reapIdle(now.Add(time.Minute)); running || len(service.instances) != 0 {
+		t.Errorf("所有浏览器空闲超时后应全部关闭: %v", service.instances)
+	}
+}
+
+func TestBrowserServiceWaitsForLaunch(t *testing.T) {
+	service := NewBrowserService(time.Minute)
+	launch := &browserLaunch{done: make(chan struct{})}
+	service.launching[true] = launch
+
+	// 其他租用者启动浏览器期间不持有锁，等待的租用者可以随 context 结束
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := service.Acquire(ctx, true)
+		done <- err
+	}()
+
+	service.SetIdleTimeout(2 * time.Minute)
+	if running := service.reapIdle(time.Now()); running {
+		t.Error("没有浏览器时空闲检查应退出")
+	}
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望错误 %v，实际为 %v", context.DeadlineExceeded, err)
+	}
+	close(launch.done)
+}
+
+func TestBrowserServiceLease(t *testing.T) {
+	requireBrowser(t)
+
+	service := NewBrowserService(time.Minute)
+	defer service.Close()
+
+	first, err := service.Acquire(context.Background(), true)
+	if err != nil {
+		t.Fatalf("租用浏览器失败: %v", err)
+	}
+	if _, err := first.Page("about:blank"); err != nil {
+		t.Fatalf("打开页面失败: %v", err)
+	}
+
+	// 第二个租约复用同一个浏览器
+	ctx, cancel := context.WithCancel(context.Background())
+	second, err := service.Acquire(ctx, true)
+	if err != nil {
+		t.Fatalf("租用浏览器失败: %v", err)
+	}
+	if second.instance != first.instance || first.instance.leases != 2 {
+		t.Fatalf("没有复用已启动的浏览器")
+	}
+
+	first.Release()
+	first.Release()
+	if pages, err := first.instance.browser.Pages(); err != nil || len(pages) != 0 {
+		t.Errorf("归还租约后页面没有关闭: %d %v", len(pages), err)
+	}
+
+	// context 结束时自动归还
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for leases(service, second) != 0 && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if n := leases(service, second); n != 0 {
+		t.Errorf("context 结束后租约没有归还，剩余 %d", n)
+	}
+
+	// 浏览器崩溃后重新启动
+	crashed := first.instance
+	crashed.launcher.Kill()
+	third, err := service.Acquire(context.Background(), true)
+	if err != nil {
+		t.Fatalf("浏览器崩溃后重新租用失败: %v", err)
+	}
+	defer third.Release()
+	if third.instance == crashed {
+		t.Error("浏览器崩溃后没有重新启动")
+	}
+}
+
+func TestBrowserServiceFromContext(t *testing.T) {
+	// 没有浏览器服务时不启动浏览器，直接返回错误
+	if _, _, err := openBrowserPage(context.Background(), "about:blank", true); !errors.Is(err, errNoBrowserService) {
+		t.Errorf("期望错误 %v，实际为 %v", errNoBrowserService, err)
+	}
+
+	// 每个 App 持有自己的浏览器服务，并通过操作的 context 传给登录驱动
+	first, second := NewApp(), NewApp()
+	if first.browsers == second.browsers {
+		t.Error("不同的 App 共用了同一个浏览器服务")
+	}
+	if service, err := browserServiceFrom(first.ops); err != nil || service != first.browsers {
+		t.Errorf("操作的 context 中没有 App 的浏览器服务: %v", err)
+	}
+}
+
+// leases 读取租约所在浏览器的租用数
+func leases(service *BrowserService, lease *BrowserLease) int {
+	service.mu.Lock()
+	defer service.mu.Unlock()
+	return lease.instance.leases
+}
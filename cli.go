@@ -0,0 +1,538 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// 命令行模式的退出码
+const (
+	exitOK      = 0 // 执行成功
+	exitFailure = 1 // 执行失败
+	exitUsage   = 2 // 参数错误
+	exitOffline = 3 // 网络未连接或需要认证
+)
+
+// cliCommand 命令行子命令
+type cliCommand struct {
+	Usage string
+	Run   func(app *App, out *cliOutput, args []string) int
+}
+
+// configUsage config 子命令的用法
+const configUsage = "config get [key] | config set <key> <value>"
+
+// profileUsage profile 子命令的用法
+const profileUsage = "profile list | profile use <name> | profile add <name> | profile remove <name>"
+
+// cliCommands 所有可用的子命令
+var cliCommands = map[string]cliCommand{
+	"login":     {Usage: "login [--profile name]  执行自动登录", Run: cliLogin},
+	"logout":    {Usage: "logout             注销当前在线用户", Run: cliLogout},
+	"status":    {Usage: "status             获取网络状态", Run: cliStatus},
+	"session":   {Usage: "session            查看在线用户、流量与余额", Run: cliSession},
+	"recipes":   {Usage: "recipes            列出内置与自定义的登录脚本", Run: cliRecipes},
+	"operators": {Usage: "operators          列出认证页面提供的运营商", Run: cliOperators},
+	"browser":   {Usage: "browser            显示浏览器模拟登录使用的浏览器", Run: cliBrowser},
+	"detect":    {Usage: "detect [--save]    检测校园网登录页面", Run: cliDetect},
+	"test":      {Usage: "test               测试登录页面，不实际登录", Run: cliTest},
+	"config":    {Usage: configUsage, Run: cliConfig},
+	"profile":   {Usage: profileUsage, Run: cliProfile},
+	"daemon":    {Usage: "daemon [--interval 60s]  前台运行网络守护", Run: cliDaemon},
+}
+
+// isCLICommand 判断参数是否为命令行子命令，用于决定是否启动界面
+func isCLICommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "help", "-h", "--help":
+		return true
+	}
+	_, ok := cliCommands[args[0]]
+	return ok
+}
+
+// runCLI 命令行模式入口，返回进程退出码
+func runCLI(args []string) int {
+	attachParentConsole()
+	log.SetOutput(os.Stderr)
+	return execCLI(args, os.Stdout, os.Stderr)
+}
+
+// execCLI 执行子命令，结果写入 stdout，错误与进度写入 stderr
+func execCLI(args []string, stdout, stderr io.Writer) int {
+	command, ok := cliCommands[args[0]]
+	if !ok {
+		printUsage(stdout)
+		return exitOK
+	}
+
+	// --json 对所有子命令生效，其余参数交给子命令自己解析
+	out := &cliOutput{json: hasFlag(args[1:], "json"), stdout: stdout, stderr: stderr}
+	app := NewApp()
+	// Ctrl+C 时中止进行中的登录与检测，随后照常关闭浏览器
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(interrupted, app.cancelOps)
+
+	code := command.Run(app, out, removeFlag(args[1:], "json"))
+	// 命令执行完毕后关闭共享的浏览器，不等待空闲超时
+	app.browsers.Close()
+	if out.err != nil && code == exitOK {
+		return exitFailure
+	}
+	return code
+}
+
+// printUsage 输出帮助信息
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "用法: YzuAutologin <命令> [--json]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "不带命令运行时启动图形界面。可用命令:")
+
+	names := make([]string, 0, len(cliCommands))
+	for name := range cliCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\n", cliCommands[name].Usage)
+	}
+}
+
+// hasFlag 判断参数中是否包含 --name 或 -name
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--"+name || arg == "-"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFlag 移除指定的标志
+func removeFlag(args []string, name string) []string {
+	var result []string
+	for _, arg := range args {
+		if arg != "--"+name && arg != "-"+name {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+// cliOutput 根据 --json 选择输出格式
+type cliOutput struct {
+	json   bool
+	stdout io.Writer
+	stderr io.Writer
+	// err 输出结果失败时的错误，命令本身成功时退出码改为执行失败
+	err error
+}
+
+// result 输出执行结果，text 为可读格式，data 为 JSON 格式
+func (o *cliOutput) result(text string, data interface{}) {
+	if o.json {
+		encoder := json.NewEncoder(o.stdout)
+		encoder.SetIndent("", "  ")
+		o.check(encoder.Encode(data))
+		return
+	}
+	fmt.Fprintln(o.stdout, text)
+}
+
+// check 记录 JSON 输出失败的错误
+func (o *cliOutput) check(err error) {
+	if err != nil {
+		fmt.Fprintln(o.stderr, "错误: 输出 JSON 失败:", err)
+		o.err = err
+	}
+}
+
+// usage 输出子命令的用法并返回参数错误的退出码
+func (o *cliOutput) usage(usage string) int {
+	fmt.Fprintln(o.stderr, "用法:", usage)
+	return exitUsage
+}
+
+// fail 输出错误信息并返回退出码
+func (o *cliOutput) fail(err error, code int) int {
+	if o.json {
+		o.result("", map[string]interface{}{"ok": false, "error": err.Error()})
+	} else {
+		fmt.Fprintln(o.stderr, "错误:", err)
+	}
+	return code
+}
+
+// progress 将登录步骤进度输出到标准错误，JSON 模式下每个事件一行，不影响标准输出中的结果
+func (o *cliOutput) progress(event StepEvent) {
+	if o.json {
+		o.check(json.NewEncoder(o.stderr).Encode(event))
+		return
+	}
+
+	line := fmt.Sprintf("[%s] %s (第 %d 次, %dms)", stepStatusText[event.Status], event.Step, event.Attempt, event.ElapsedMs)
+	if event.Error != "" {
+		line += ": " + event.Error
+	}
+	fmt.Fprintln(o.stderr, line)
+}
+
+// stepStatusText 命令行显示的步骤状态
+var stepStatusText = map[string]string{
+	StepStarted:   "开始",
+	StepRetrying:  "重试",
+	StepSucceeded: "完成",
+	StepFailed:    "失败",
+}
+
+func cliLogin(app *App, out *cliOutput, args []string) int {
+	flags := flag.NewFlagSet("login", flag.ContinueOnError)
+	profile := flags.String("profile", "", "使用指定账号登录，默认使用默认账号")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	defer app.subscribeSteps(out.progress)()
+	result, err := app.LoginWithProfile(*profile)
+	if err != nil {
+		if out.json && result != nil {
+			out.result("", result)
+			return exitFailure
+		}
+		return out.fail(err, exitFailure)
+	}
+
+	out.result(formatLoginResult("登录结果", result), result)
+	return exitOK
+}
+
+func cliLogout(app *App, out *cliOutput, args []string) int {
+	defer app.subscribeSteps(out.progress)()
+	result, err := app.Logout()
+	if err != nil {
+		if out.json && result != nil {
+			out.result("", result)
+			return exitFailure
+		}
+		return out.fail(err, exitFailure)
+	}
+
+	out.result(formatLoginResult("注销结果", result), result)
+	return exitOK
+}
+
+// formatLoginResult 将登录或注销结果格式化为可读文本
+func formatLoginResult(title string, result *LoginResult) string {
+	lines := []string{fmt.Sprintf(title+": %s (驱动: %s, 耗时: %dms, 重试: %d 次)",
+		result.Outcome, result.Driver, result.DurationMs, result.Retries)}
+	for _, step := range result.Steps {
+		line := fmt.Sprintf("  - %s: %dms, 尝试 %d 次", step.Name, step.DurationMs, step.Attempts)
+		if step.Error != "" {
+			line += ", 错误: " + step.Error
+		}
+		lines = append(lines, line)
+	}
+	for i, attempt := range result.Attempts {
+		line := fmt.Sprintf("  %d. %s:%s %s, %dms", i+1, attempt.Profile, attempt.Operator, attempt.Outcome, attempt.DurationMs)
+		if attempt.Error != "" {
+			line += ", 错误: " + attempt.Error
+		}
+		lines = append(lines, line)
+	}
+	if result.Fallback > 0 {
+		lines = append(lines, fmt.Sprintf("故障转移: 第 %d 个备用步骤 %s:%s 登录成功", result.Fallback, result.Profile, result.Operator))
+	}
+	if result.IP != "" {
+		lines = append(lines, "IP: "+result.IP)
+	}
+	if result.Message != "" {
+		lines = append(lines, "提示: "+result.Message)
+	}
+	if result.FinalURL != "" {
+		lines = append(lines, "最终页面: "+result.FinalURL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func cliStatus(app *App, out *cliOutput, args []string) int {
+	status, err := app.GetNetworkStatus()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+
+	text := fmt.Sprintf("%v", status["connectivity_result"])
+	if loginURL, ok := status["login_url"]; ok {
+		text += fmt.Sprintf("\n登录页面: %v", loginURL)
+	}
+	if name, ok := status["vendor_name"]; ok {
+		text += fmt.Sprintf("\n认证系统: %v %v", name, status["vendor_version"])
+	}
+	out.result(text, status)
+
+	if connected, _ := status["connected"].(bool); !connected {
+		return exitOffline
+	}
+	return exitOK
+}
+
+func cliSession(app *App, out *cliOutput, args []string) int {
+	info, err := app.GetSessionInfo()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+
+	if !info.Online {
+		out.result("当前没有在线的认证用户", info)
+		return exitOffline
+	}
+
+	out.result(formatSessionInfo(info), info)
+	return exitOK
+}
+
+// formatSessionInfo 将在线信息格式化为可读文本，未返回的项目不显示
+func formatSessionInfo(info *SessionInfo) string {
+	fields := []struct{ label, value string }{
+		{"用户", strings.TrimSpace(info.UserName + " " + info.UserID)},
+		{"IP", info.IP},
+		{"MAC", info.MAC},
+		{"运营商", info.Service},
+		{"在线时长", info.OnlineTime},
+		{"已用流量", info.UsedTraffic},
+		{"账户余额", info.Balance},
+	}
+
+	var lines []string
+	for _, field := range fields {
+		if field.value != "" {
+			lines = append(lines, field.label+": "+field.value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func cliOperators(app *App, out *cliOutput, args []string) int {
+	operators, err := app.ListOperators()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+
+	lines := make([]string, len(operators))
+	for i, operator := range operators {
+		lines[i] = fmt.Sprintf("%-12s %s (%s)", operator.ID, operator.Label, operator.Value)
+	}
+	out.result(strings.Join(lines, "\n"), operators)
+	return exitOK
+}
+
+func cliBrowser(app *App, out *cliOutput, args []string) int {
+	info, err := app.GetBrowser()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+
+	out.result(fmt.Sprintf("%s (%s)", info.Path, info.Source), info)
+	return exitOK
+}
+
+func cliRecipes(app *App, out *cliOutput, args []string) int {
+	recipes, err := app.ListRecipes()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+
+	lines := make([]string, len(recipes))
+	for i, recipe := range recipes {
+		lines[i] = fmt.Sprintf("%-12s %d 步  %s", recipe.Name, len(recipe.Steps), recipe.Source)
+		if recipe.Description != "" {
+			lines[i] += "\n             " + recipe.Description
+		}
+	}
+	out.result(strings.Join(lines, "\n"), recipes)
+	return exitOK
+}
+
+func cliDetect(app *App, out *cliOutput, args []string) int {
+	flags := flag.NewFlagSet("detect", flag.ContinueOnError)
+	save := flags.Bool("save", false, "将检测到的登录页面保存到配置")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	detect := app.DetectNetworkLoginPage
+	if *save {
+		detect = app.AutoDetectAndSaveLoginURL
+	}
+
+	loginURL, err := detect()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+
+	out.result(loginURL, map[string]interface{}{"ok": true, "login_url": loginURL, "saved": *save})
+	return exitOK
+}
+
+func cliTest(app *App, out *cliOutput, args []string) int {
+	result, err := app.TestConnection()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+
+	out.result(result, map[string]interface{}{"ok": true, "result": result})
+	return exitOK
+}
+
+func cliConfig(app *App, out *cliOutput, args []string) int {
+	if len(args) == 0 {
+		return out.usage(configUsage)
+	}
+
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+	fields := config.Fields()
+	// 读取配置时隐藏密码
+	if fields["passwordindex"] != "" {
+		fields["passwordindex"] = "******"
+	}
+
+	switch args[0] {
+	case "get":
+		if len(args) > 1 {
+			value, ok := fields[args[1]]
+			if !ok {
+				return out.fail(fmt.Errorf("配置项不存在: %s", args[1]), exitUsage)
+			}
+			out.result(value, map[string]string{args[1]: value})
+			return exitOK
+		}
+
+		keys := make([]string, 0, len(fields))
+		for key := range fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		text := ""
+		for _, key := range keys {
+			text += fmt.Sprintf("%s=%s\n", key, fields[key])
+		}
+		out.result(strings.TrimSuffix(text, "\n"), fields)
+		return exitOK
+
+	case "set":
+		if len(args) != 3 {
+			return out.usage(configUsage)
+		}
+
+		if err := config.SetField(args[1], args[2]); err != nil {
+			return out.fail(err, exitUsage)
+		}
+		if err := SaveConfig(config); err != nil {
+			return out.fail(err, exitFailure)
+		}
+		if args[1] == "passwordindex" && secretStoreIsWeak() {
+			fmt.Fprintln(out.stderr, "警告:", weakSecretWarning)
+		}
+		out.result(fmt.Sprintf("已设置 %s", args[1]), map[string]interface{}{"ok": true, "key": args[1]})
+		return exitOK
+	}
+
+	return out.usage(configUsage)
+}
+
+func cliProfile(app *App, out *cliOutput, args []string) int {
+	if len(args) == 0 || (args[0] != "list" && len(args) != 2) {
+		return out.usage(profileUsage)
+	}
+
+	if args[0] == "use" {
+		if _, err := app.SwitchProfile(args[1]); err != nil {
+			return out.fail(err, exitFailure)
+		}
+		out.result("已切换到账号 "+args[1], map[string]interface{}{"ok": true, "default_profile": args[1]})
+		return exitOK
+	}
+
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return out.fail(err, exitFailure)
+	}
+
+	switch args[0] {
+	case "list":
+		var lines []string
+		for _, profile := range config.Profiles {
+			mark := " "
+			if profile.Name == config.DefaultProfile {
+				mark = "*"
+			}
+			lines = append(lines, fmt.Sprintf("%s %s\t%s\t%s", mark, profile.Name, profile.Countindex, profile.Operatorindex))
+		}
+		profiles, _ := app.ListProfiles()
+		out.result(strings.Join(lines, "\n"), map[string]interface{}{"default_profile": config.DefaultProfile, "profiles": profiles})
+		return exitOK
+
+	case "add":
+		if config.profileIndex(args[1]) >= 0 {
+			return out.fail(fmt.Errorf("账号已存在: %s", args[1]), exitFailure)
+		}
+		// 新账号通常使用同一个认证页面
+		config.Profiles = append(config.Profiles, Profile{Name: args[1], Webindex: config.Webindex})
+
+	case "remove":
+		if err := app.RemoveProfile(args[1]); err != nil {
+			return out.fail(err, exitFailure)
+		}
+		out.result("已删除账号 "+args[1], map[string]interface{}{"ok": true, "profile": args[1]})
+		return exitOK
+
+	default:
+		return out.usage(profileUsage)
+	}
+
+	if err := SaveConfig(config); err != nil {
+		return out.fail(err, exitFailure)
+	}
+	out.result("已添加账号 "+args[1], map[string]interface{}{"ok": true, "profile": args[1]})
+	return exitOK
+}
+
+func cliDaemon(app *App, out *cliOutput, args []string) int {
+	flags := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	interval := flags.Duration("interval", watchdogInterval, "网络检测间隔")
+	if err := flags.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *interval <= 0 {
+		return out.fail(fmt.Errorf("检测间隔必须大于 0: %v", *interval), exitUsage)
+	}
+
+	if config, err := LoadConfigOrDefault(); err == nil {
+		app.browsers.SetIdleTimeout(config.browserIdleTimeout())
+	}
+
+	watchdog := NewWatchdog(*interval, app.watchdogLogin)
+	watchdog.Start()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+
+	watchdog.Stop()
+	return exitOK
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// runTestCLI 执行子命令，返回退出码与标准输出、标准错误的内容
+func runTestCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := execCLI(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLIConfigRoundTrip(t *testing.T) {
+	saveTestConfig(t, newMockPortal(t, scenarioSuccess), "http", "correct-password")
+
+	code, stdout, stderr := runTestCLI(t, "config", "set", "driver", "rod", "--json")
+	if code != exitOK {
+		t.Fatalf("设置配置项的退出码为 %d: %s", code, stderr)
+	}
+	var set struct {
+		OK  bool   `json:"ok"`
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal([]byte(stdout), &set); err != nil || !set.OK || set.Key != "driver" {
+		t.Errorf("设置配置项的 JSON 输出不正确: %q %v", stdout, err)
+	}
+
+	code, stdout, stderr = runTestCLI(t, "config", "get", "driver", "--json")
+	if code != exitOK {
+		t.Fatalf("读取配置项的退出码为 %d: %s", code, stderr)
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil || got["driver"] != "rod" {
+		t.Errorf("读取的配置项不正确: %q %v", stdout, err)
+	}
+
+	code, stdout, _ = runTestCLI(t, "config", "get", "driver")
+	if code != exitOK || strings.TrimSpace(stdout) != "rod" {
+		t.Errorf("可读格式的输出不正确: %d %q", code, stdout)
+	}
+
+	// 列出全部配置时隐藏密码
+	code, stdout, _ = runTestCLI(t, "config", "get", "--json")
+	if code != exitOK {
+		t.Fatalf("列出配置的退出码为 %d", code)
+	}
+	got = nil
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil || got["passwordindex"] != "******" {
+		t.Errorf("列出配置时没有隐藏密码: %q %v", stdout, err)
+	}
+
+	// 加密文件没有口令保护时提示密码可被本机程序读取
+	t.Setenv(secretPassphraseEnv, "")
+	code, _, stderr = runTestCLI(t, "config", "set", "passwordindex", "correct-password")
+	if code != exitOK || !strings.Contains(stderr, secretPassphraseEnv) {
+		t.Errorf("没有口令时应提示: %d %q", code, stderr)
+	}
+	t.Setenv(secretPassphraseEnv, "test-passphrase")
+	code, _, stderr = runTestCLI(t, "config", "set", "passwordindex", "correct-password")
+	if code != exitOK || stderr != "" {
+		t.Errorf("设置口令后不应提示: %d %q", code, stderr)
+	}
+
+	code, stdout, _ = runTestCLI(t, "config", "get", "passwordindex")
+	if code != exitOK || strings.TrimSpace(stdout) != "******" {
+		t.Errorf("读取单个配置项时没有隐藏密码: %d %q", code, stdout)
+	}
+}
+
+func TestCLIUsageErrors(t *testing.T) {
+	saveTestConfig(t, newMockPortal(t, scenarioSuccess), "http", "correct-password")
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"config 缺少子命令", []string{"config"}},
+		{"config 未知子命令", []string{"config", "reset"}},
+		{"config set 缺少值", []string{"config", "set", "driver"}},
+		{"config set 参数过多", []string{"config", "set", "driver", "http", "rod"}},
+		{"config set 未知配置项", []string{"config", "set", "nosuchkey", "x"}},
+		{"config set 值不合法", []string{"config", "set", "watchdog", "maybe"}},
+		{"config get 未知配置项", []string{"config", "get", "nosuchkey"}},
+		{"profile 缺少名称", []string{"profile", "use"}},
+		{"daemon 间隔为 0", []string{"daemon", "--interval", "0"}},
+		{"daemon 间隔为负数", []string{"daemon", "--interval", "-1s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runTestCLI(t, tt.args...)
+			if code != exitUsage {
+				t.Errorf("期望退出码 %d，实际为 %d", exitUsage, code)
+			}
+			if stdout != "" || stderr == "" {
+				t.Errorf("参数错误应只输出到标准错误: stdout=%q stderr=%q", stdout, stderr)
+			}
+		})
+	}
+}
+
+func TestCLIStatusOffline(t *testing.T) {
+	portal := newMockPortal(t, scenarioSuccess)
+	saveTestConfig(t, portal, "http", "correct-password")
+	// 连通性检测被劫持到模拟认证页面
+	setConnectivityProbe(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<script>top.self.location.href='` + portal.LoginURL() + `'</script>`))
+	})
+
+	code, stdout, stderr := runTestCLI(t, "status", "--json")
+	if code != exitOffline {
+		t.Fatalf("期望退出码 %d，实际为 %d: %s", exitOffline, code, stderr)
+	}
+	var status map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &status); err != nil {
+		t.Fatalf("状态输出不是合法的 JSON: %q %v", stdout, err)
+	}
+	if status["connected"] != false || status["needs_authentication"] != true || status["login_url"] != portal.LoginURL() {
+		t.Errorf("网络状态不正确: %v", status)
+	}
+	if status["vendor"] != string(VendorRuijie) {
+		t.Errorf("认证系统识别不正确: %v", status["vendor"])
+	}
+}
+
+func TestCLIOutputEncodeError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	out := &cliOutput{json: true, stdout: &stdout, stderr: &stderr}
+
+	out.result("", map[string]interface{}{"invalid": make(chan int)})
+	if out.err == nil || stderr.Len() == 0 {
+		t.Errorf("JSON 编码失败时应记录错误: %v %q", out.err, stderr.String())
+	}
+
+	// 进度事件写入失败同样记录错误
+	out = &cliOutput{json: true, stdout: &stdout, stderr: failingWriter{}}
+	out.progress(StepEvent{Step: "填写用户名", Status: StepStarted})
+	if out.err == nil {
+		t.Error("进度输出失败时应记录错误")
+	}
+}
+
+// failingWriter 写入总是失败的输出
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("输出已关闭")
+}
@@ -0,0 +1,600 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// currentConfigVersion 当前配置文件结构版本，修改结构时递增并添加迁移函数
+const currentConfigVersion = 4
+
+// configFileName 配置文件名
+const configFileName = "data.json"
+
+// defaultProfileName 从旧版本迁移或首次保存时创建的账号名称
+const defaultProfileName = "默认"
+
+// Profile 一套账号配置，多人共用电脑或同一账号使用不同运营商时各建一个
+type Profile struct {
+	Name          string `json:"name"`
+	Webindex      string `json:"webindex"`
+	Countindex    string `json:"countindex"`
+	Passwordindex string `json:"passwordindex,omitempty"`
+	Operatorindex string `json:"operatorindex"`
+}
+
+// Config 用户配置，前端、命令行与登录流程共用同一结构。
+// Webindex、Countindex、Passwordindex、Operatorindex 为 DefaultProfile 指向的账号，
+// 读取时从 Profiles 展开，保存时写回，不单独写入配置文件。
+type Config struct {
+	Version        int       `json:"version"`
+	Webindex       string    `json:"webindex,omitempty"`
+	Countindex     string    `json:"countindex,omitempty"`
+	Passwordindex  string    `json:"passwordindex,omitempty"`
+	Operatorindex  string    `json:"operatorindex,omitempty"`
+	Autostartindex bool      `json:"autostartindex"`
+	Driver         string    `json:"driver"`
+	Watchdog       bool      `json:"watchdog"`
+	DefaultProfile string    `json:"default_profile"`
+	Profiles       []Profile `json:"profiles"`
+	// Failover 默认账号登录失败后依次尝试的账号与运营商，为空时不进行故障转移
+	Failover []FailoverStep `json:"failover,omitempty"`
+	// Recipe 浏览器登录使用的登录脚本，为空时按认证地址自动选择
+	Recipe string `json:"recipe,omitempty"`
+	// BrowserIdleTimeout 共享浏览器空闲多少秒后关闭，0 表示使用默认值
+	BrowserIdleTimeout int `json:"browser_idle_timeout,omitempty"`
+	// BrowserPath 浏览器可执行文件路径，为空时自动查找
+	BrowserPath string `json:"browser_path,omitempty"`
+}
+
+// configMigration 将旧版本的原始配置升级到下一个版本
+type configMigration func(raw map[string]interface{}) error
+
+// configMigrations 按起始版本索引的迁移函数
+var configMigrations = map[int]configMigration{
+	1: migrateConfigV1,
+	2: migrateConfigV2,
+	3: migrateConfigV3,
+}
+
+// migrateConfigV1 v1 中所有字段均为字符串，自动启动与网络守护改为布尔值
+func migrateConfigV1(raw map[string]interface{}) error {
+	raw["autostartindex"] = raw["autostartindex"] == "true"
+	// v1 中只有显式设置为 false 才关闭网络守护
+	raw["watchdog"] = raw["watchdog"] != "false"
+	return nil
+}
+
+// migrateConfigV2 v2 中只有一套账号，移入名为"默认"的账号配置
+func migrateConfigV2(raw map[string]interface{}) error {
+	profile := map[string]interface{}{"name": defaultProfileName}
+	for _, key := range []string{"webindex", "countindex", "passwordindex", "operatorindex"} {
+		if value, ok := raw[key]; ok {
+			profile[key] = value
+			delete(raw, key)
+		}
+	}
+	raw["profiles"] = []interface{}{profile}
+	raw["default_profile"] = defaultProfileName
+	return nil
+}
+
+// migrateConfigV3 v3 中运营商以字母 a–d 表示，改为运营商名称
+func migrateConfigV3(raw map[string]interface{}) error {
+	profiles, _ := raw["profiles"].([]interface{})
+	for _, item := range profiles {
+		if profile, ok := item.(map[string]interface{}); ok {
+			profile["operatorindex"] = migrateOperatorLetter(profile["operatorindex"])
+		}
+	}
+
+	failover, _ := raw["failover"].([]interface{})
+	for _, item := range failover {
+		if step, ok := item.(map[string]interface{}); ok && step["operator"] != nil {
+			step["operator"] = migrateOperatorLetter(step["operator"])
+		}
+	}
+	return nil
+}
+
+// migrateOperatorLetter 将字母表示的运营商转换为名称，其他值原样返回
+func migrateOperatorLetter(value interface{}) interface{} {
+	if letter, ok := value.(string); ok {
+		return normalizeOperator(letter)
+	}
+	return value
+}
+
+// normalizeOperator 去除空白，旧版本的字母 a–d 与别名转换为运营商标准名称，其他名称原样保留
+func normalizeOperator(operator string) string {
+	operator = strings.TrimSpace(operator)
+	if name, ok := legacyOperatorNames[strings.ToLower(operator)]; ok {
+		return name
+	}
+	return canonicalOperatorName(operator)
+}
+
+// DefaultConfig 返回默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Version:        currentConfigVersion,
+		Driver:         defaultLoginDriver,
+		Watchdog:       true,
+		DefaultProfile: defaultProfileName,
+	}
+}
+
+// configFilePath 返回配置文件路径
+func configFilePath() string {
+	return filepath.Join(ConfigDir(), configFileName)
+}
+
+// LoadConfig 读取配置文件，旧版本配置与明文密码会自动迁移。
+// 配置文件不存在时返回 os.ErrNotExist。
+func LoadConfig() (*Config, error) {
+	filename := configFilePath()
+	log.Println("读取配置文件:", filename)
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]interface{})
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+
+	version := 1
+	if v, ok := raw["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > currentConfigVersion {
+		return nil, fmt.Errorf("配置文件版本 %d 高于当前程序支持的版本 %d，请升级程序", version, currentConfigVersion)
+	}
+
+	migrated := version < currentConfigVersion
+	for ; version < currentConfigVersion; version++ {
+		log.Printf("迁移配置文件: v%d -> v%d", version, version+1)
+		if err := configMigrations[version](raw); err != nil {
+			return nil, fmt.Errorf("迁移配置文件 v%d 失败: %w", version, err)
+		}
+	}
+	raw["version"] = currentConfigVersion
+
+	content, err = json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	config := DefaultConfig()
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	config.normalize()
+
+	for _, profile := range config.Profiles {
+		if profile.Passwordindex != "" {
+			// 旧版本以明文保存密码，迁移后从文件中移除
+			log.Printf("检测到账号 %s 的明文密码，迁移到凭据存储...", profile.Name)
+			migrated = true
+		}
+	}
+
+	if config.profileIndex(config.DefaultProfile) < 0 {
+		// 手动编辑的配置文件可能只有账号字段，没有账号列表
+		config.storeProfile()
+		migrated = true
+	}
+	if err := config.applyProfile(config.DefaultProfile); err != nil {
+		return nil, err
+	}
+
+	if migrated {
+		if err := SaveConfig(config); err != nil {
+			log.Printf("保存迁移后的配置失败: %v", err)
+		}
+	}
+
+	// 其他账号的密码在使用时才读取，避免随配置一起返回给前端
+	for i := range config.Profiles {
+		config.Profiles[i].Passwordindex = ""
+	}
+
+	return config, nil
+}
+
+// LoadConfigOrDefault 读取配置，配置文件不存在时返回默认配置
+func LoadConfigOrDefault() (*Config, error) {
+	config, err := LoadConfig()
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultConfig(), nil
+	}
+	return config, err
+}
+
+// SaveConfig 校验并保存配置，账号字段写回默认账号，密码单独保存到凭据存储
+func SaveConfig(config *Config) error {
+	config.normalize()
+	config.storeProfile()
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	saved := *config
+	saved.Version = currentConfigVersion
+	saved.Webindex, saved.Countindex, saved.Passwordindex, saved.Operatorindex = "", "", "", ""
+	saved.Profiles = make([]Profile, len(config.Profiles))
+	for i, profile := range config.Profiles {
+		// 默认账号的密码为空时同样写入，其他账号只在填写了密码时更新。
+		// 已保存的密码无法解密时读取配置得到的是空密码，不能用它覆盖原来的密码
+		if profile.Passwordindex != "" || (profile.Name == config.DefaultProfile && !passwordUnreadable(profile.Countindex)) {
+			if err := secretStore().Set(passwordSecretKey(profile.Countindex), profile.Passwordindex); err != nil {
+				return fmt.Errorf("保存账号 %s 的密码失败: %w", profile.Name, err)
+			}
+		}
+		profile.Passwordindex = ""
+		saved.Profiles[i] = profile
+	}
+
+	content, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(configFilePath(), content, 0o600)
+}
+
+// passwordUnreadable 判断凭据存储中该学号的密码是否存在但无法解密
+func passwordUnreadable(account string) bool {
+	_, err := secretStore().Get(passwordSecretKey(account))
+	return errors.Is(err, ErrSecretUnreadable)
+}
+
+// profileIndex 返回指定名称账号的下标，不存在时返回 -1
+func (c *Config) profileIndex(name string) int {
+	for i, profile := range c.Profiles {
+		if profile.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyProfile 将指定账号展开到账号字段，密码未填写时从凭据存储读取。
+// 密码无法解密时留空，用户仍可打开设置重新输入密码
+func (c *Config) applyProfile(name string) error {
+	i := c.profileIndex(name)
+	if i < 0 {
+		return fmt.Errorf("账号配置不存在: %s", name)
+	}
+
+	profile := c.Profiles[i]
+	c.DefaultProfile = profile.Name
+	c.Webindex = profile.Webindex
+	c.Countindex = profile.Countindex
+	c.Operatorindex = profile.Operatorindex
+	c.Passwordindex = profile.Passwordindex
+	if c.Passwordindex != "" {
+		return nil
+	}
+
+	password, err := secretStore().Get(passwordSecretKey(profile.Countindex))
+	if errors.Is(err, ErrSecretUnreadable) {
+		log.Printf("账号 %s 的密码无法读取，需要重新输入: %v", profile.Name, err)
+		password, err = "", nil
+	}
+	if err != nil && !errors.Is(err, ErrSecretNotFound) {
+		return fmt.Errorf("读取密码失败: %w", err)
+	}
+	c.Passwordindex = password
+	return nil
+}
+
+// storeProfile 将账号字段写回默认账号，默认账号不存在时新建
+func (c *Config) storeProfile() {
+	profile := Profile{
+		Name:          c.DefaultProfile,
+		Webindex:      c.Webindex,
+		Countindex:    c.Countindex,
+		Passwordindex: c.Passwordindex,
+		Operatorindex: c.Operatorindex,
+	}
+
+	if i := c.profileIndex(c.DefaultProfile); i >= 0 {
+		c.Profiles[i] = profile
+		return
+	}
+	c.Profiles = append(c.Profiles, profile)
+}
+
+// ForProfile 返回以指定账号作为当前账号的配置副本，用于登录，不修改原配置
+func (c *Config) ForProfile(name string) (*Config, error) {
+	view := *c
+	view.Profiles = append([]Profile(nil), c.Profiles...)
+	if err := view.applyProfile(name); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// ProfileNames 返回所有账号名称
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for _, profile := range c.Profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// normalize 规范化用户输入并补全默认值
+func (c *Config) normalize() {
+	c.Webindex = strings.TrimSpace(c.Webindex)
+	c.Countindex = strings.TrimSpace(c.Countindex)
+	c.Operatorindex = normalizeOperator(c.Operatorindex)
+	c.Recipe = strings.TrimSpace(c.Recipe)
+	c.BrowserPath = strings.TrimSpace(c.BrowserPath)
+	c.Driver = strings.TrimSpace(c.Driver)
+	if c.Driver == "" {
+		c.Driver = defaultLoginDriver
+	}
+
+	c.DefaultProfile = strings.TrimSpace(c.DefaultProfile)
+	if c.DefaultProfile == "" {
+		c.DefaultProfile = defaultProfileName
+	}
+	for i := range c.Profiles {
+		c.Profiles[i].normalize()
+	}
+	for i := range c.Failover {
+		c.Failover[i].Profile = strings.TrimSpace(c.Failover[i].Profile)
+		c.Failover[i].Operator = normalizeOperator(c.Failover[i].Operator)
+	}
+}
+
+// normalize 规范化账号配置
+func (p *Profile) normalize() {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Webindex = strings.TrimSpace(p.Webindex)
+	p.Countindex = strings.TrimSpace(p.Countindex)
+	p.Operatorindex = normalizeOperator(p.Operatorindex)
+}
+
+// Validate 校验账号配置，未填写的字段视为尚未配置
+func (p *Profile) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("账号名称不能为空")
+	}
+
+	if p.Webindex != "" {
+		u, err := url.Parse(p.Webindex)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("认证地址无效: %s", p.Webindex)
+		}
+	}
+
+	return nil
+}
+
+// Validate 校验配置是否有效，未填写的字段视为尚未配置
+func (c *Config) Validate() error {
+	seen := make(map[string]bool, len(c.Profiles))
+	for _, profile := range c.Profiles {
+		if err := profile.Validate(); err != nil {
+			return fmt.Errorf("账号 %s: %w", profile.Name, err)
+		}
+		if seen[profile.Name] {
+			return fmt.Errorf("账号名称重复: %s", profile.Name)
+		}
+		seen[profile.Name] = true
+	}
+	if !seen[c.DefaultProfile] {
+		return fmt.Errorf("默认账号不存在: %s", c.DefaultProfile)
+	}
+
+	for _, step := range c.Failover {
+		if !seen[step.Profile] {
+			return fmt.Errorf("故障转移步骤 %s 使用的账号不存在: %s", step, step.Profile)
+		}
+	}
+
+	if _, ok := loginDrivers[c.Driver]; !ok {
+		return fmt.Errorf("未知的登录驱动: %s (可用: %v)", c.Driver, LoginDriverNames())
+	}
+
+	if c.BrowserIdleTimeout < 0 {
+		return fmt.Errorf("浏览器空闲超时不能为负数: %d", c.BrowserIdleTimeout)
+	}
+
+	return nil
+}
+
+// String 打印配置时隐藏密码
+func (c Config) String() string {
+	password := ""
+	if c.Passwordindex != "" {
+		password = "******"
+	}
+	return fmt.Sprintf("{Version:%d Profile:%s Webindex:%s Countindex:%s Passwordindex:%s Operatorindex:%s Autostartindex:%v Driver:%s Watchdog:%v Profiles:%v Failover:%s Recipe:%s BrowserIdleTimeout:%d BrowserPath:%s}",
+		c.Version, c.DefaultProfile, c.Webindex, c.Countindex, password, c.Operatorindex, c.Autostartindex, c.Driver, c.Watchdog, c.ProfileNames(), formatFailoverSteps(c.Failover), c.Recipe, c.BrowserIdleTimeout, c.BrowserPath)
+}
+
+// browserIdleTimeout 返回共享浏览器的空闲超时，未配置时返回 0，由浏览器服务使用默认值
+func (c *Config) browserIdleTimeout() time.Duration {
+	return time.Duration(c.BrowserIdleTimeout) * time.Second
+}
+
+// Fields 以字符串形式返回所有配置项，供命令行使用
+func (c *Config) Fields() map[string]string {
+	return map[string]string{
+		"webindex":             c.Webindex,
+		"countindex":           c.Countindex,
+		"passwordindex":        c.Passwordindex,
+		"operatorindex":        c.Operatorindex,
+		"autostartindex":       strconv.FormatBool(c.Autostartindex),
+		"driver":               c.Driver,
+		"watchdog":             strconv.FormatBool(c.Watchdog),
+		"failover":             formatFailoverSteps(c.Failover),
+		"recipe":               c.Recipe,
+		"browser_idle_timeout": strconv.Itoa(c.BrowserIdleTimeout),
+		"browser_path":         c.BrowserPath,
+	}
+}
+
+// SetField 按 JSON 键名修改配置项，供命令行使用
+func (c *Config) SetField(key, value string) error {
+	switch key {
+	case "webindex":
+		c.Webindex = value
+	case "countindex":
+		c.Countindex = value
+	case "passwordindex":
+		c.Passwordindex = value
+	case "operatorindex":
+		c.Operatorindex = value
+	case "driver":
+		c.Driver = value
+	case "failover":
+		c.Failover = parseFailoverSteps(value)
+	case "recipe":
+		c.Recipe = value
+	case "browser_idle_timeout":
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return fmt.Errorf("%s 只能是不小于 0 的秒数", key)
+		}
+		c.BrowserIdleTimeout = seconds
+	case "browser_path":
+		c.BrowserPath = value
+	case "autostartindex", "watchdog":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%s 只能是 true 或 false", key)
+		}
+		if key == "autostartindex" {
+			c.Autostartindex = enabled
+		} else {
+			c.Watchdog = enabled
+		}
+	default:
+		return fmt.Errorf("配置项不存在: %s", key)
+	}
+	return nil
+}
+
+// GetConfig 获取当前配置
+func (a *App) GetConfig() (*Config, error) {
+	return LoadConfigOrDefault()
+}
+
+// UpdateConfig 校验并保存配置，被删除账号的密码一并从凭据存储删除
+func (a *App) UpdateConfig(config Config) error {
+	if err := replaceConfig(&config); err != nil {
+		return err
+	}
+	a.browsers.SetIdleTimeout(config.browserIdleTimeout())
+	return nil
+}
+
+// replaceConfig 保存配置，并删除不再被任何账号使用的密码
+func replaceConfig(config *Config) error {
+	previous, err := LoadConfigOrDefault()
+	if err := SaveConfig(config); err != nil {
+		return err
+	}
+	if err == nil {
+		deleteUnusedPasswords(previous, config)
+	}
+	return nil
+}
+
+// deleteUnusedPasswords 删除 previous 中有、config 中已没有账号使用的密码。
+// 多个账号配置可以使用同一个学号，仍被使用的密码保留
+func deleteUnusedPasswords(previous, config *Config) {
+	used := make(map[string]bool, len(config.Profiles))
+	for _, profile := range config.Profiles {
+		used[profile.Countindex] = true
+	}
+	for _, profile := range previous.Profiles {
+		if profile.Countindex == "" || used[profile.Countindex] {
+			continue
+		}
+		used[profile.Countindex] = true
+		if err := secretStore().Delete(passwordSecretKey(profile.Countindex)); err != nil {
+			log.Printf("删除账号 %s 的密码失败: %v", profile.Name, err)
+		}
+	}
+}
+
+// ListProfiles 列出所有账号配置，不包含密码
+func (a *App) ListProfiles() ([]Profile, error) {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]Profile, len(config.Profiles))
+	for i, profile := range config.Profiles {
+		profile.Passwordindex = ""
+		profiles[i] = profile
+	}
+	return profiles, nil
+}
+
+// SwitchProfile 切换默认账号，自动启动与网络守护随之使用该账号，返回切换后的配置
+func (a *App) SwitchProfile(name string) (*Config, error) {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.applyProfile(name); err != nil {
+		return nil, err
+	}
+	if err := SaveConfig(config); err != nil {
+		return nil, err
+	}
+
+	log.Printf("已切换到账号: %s", name)
+	return config, nil
+}
+
+// RemoveProfile 删除账号配置，不能删除默认账号与故障转移中使用的账号；
+// 该账号的密码不再被其他账号使用时一并删除
+func (a *App) RemoveProfile(name string) error {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return err
+	}
+
+	i := config.profileIndex(name)
+	if i < 0 {
+		return fmt.Errorf("账号配置不存在: %s", name)
+	}
+	if name == config.DefaultProfile {
+		return fmt.Errorf("不能删除默认账号，请先切换到其他账号")
+	}
+	var referenced []FailoverStep
+	for _, step := range config.Failover {
+		if step.Profile == name {
+			referenced = append(referenced, step)
+		}
+	}
+	if len(referenced) > 0 {
+		return fmt.Errorf("账号 %s 仍在故障转移中使用 (%s)，请先从 failover 中移除", name, formatFailoverSteps(referenced))
+	}
+	config.Profiles = append(config.Profiles[:i], config.Profiles[i+1:]...)
+	if err := replaceConfig(config); err != nil {
+		return err
+	}
+
+	log.Printf("已删除账号: %s", name)
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// configDirEnv 指定配置目录的环境变量
+const configDirEnv = "YZU_AUTOLOGIN_CONFIG_DIR"
+
+// portableEnv 设置为 1 或 true 时启用便携模式
+const portableEnv = "YZU_AUTOLOGIN_PORTABLE"
+
+// portableMarker 可执行文件旁存在该文件时启用便携模式，配置保存在程序目录
+const portableMarker = "portable"
+
+var (
+	// configDirFlag 命令行 --config-dir 指定的配置目录
+	configDirFlag string
+	// portableFlag 命令行 --portable 启用便携模式
+	portableFlag bool
+
+	configDirOnce  sync.Once
+	configDirValue string
+)
+
+// ConfigDir 返回配置目录，按以下顺序确定：
+//  1. 命令行 --config-dir
+//  2. 环境变量 YZU_AUTOLOGIN_CONFIG_DIR
+//  3. 便携模式（--portable、YZU_AUTOLOGIN_PORTABLE 或程序目录下的 portable 文件）使用程序目录
+//  4. 用户配置目录：%APPDATA%\YzuAutologin、~/Library/Application Support/YzuAutologin、~/.config/yzuautologin
+func ConfigDir() string {
+	configDirOnce.Do(func() {
+		configDirValue = resolveConfigDir()
+		if err := os.MkdirAll(configDirValue, 0o700); err != nil {
+			log.Printf("创建配置目录失败: %v", err)
+		}
+		migrateLegacyConfig(configDirValue)
+		log.Println("配置目录:", configDirValue)
+	})
+	return configDirValue
+}
+
+// executableDir 返回可执行文件所在目录
+func executableDir() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "."
+	}
+	return filepath.Dir(exePath)
+}
+
+// resolveConfigDir 计算配置目录
+func resolveConfigDir() string {
+	if configDirFlag != "" {
+		return configDirFlag
+	}
+	if dir := os.Getenv(configDirEnv); dir != "" {
+		return dir
+	}
+	if isPortable() {
+		return executableDir()
+	}
+
+	base, err := os.UserConfigDir()
+	if err != nil {
+		log.Printf("无法获取用户配置目录，使用程序目录: %v", err)
+		return executableDir()
+	}
+
+	// Linux 下遵循 XDG 习惯使用小写目录名
+	name := "YzuAutologin"
+	if runtime.GOOS != "windows" && runtime.GOOS != "darwin" {
+		name = "yzuautologin"
+	}
+	return filepath.Join(base, name)
+}
+
+// isPortable 判断是否启用便携模式
+func isPortable() bool {
+	if portableFlag {
+		return true
+	}
+	switch strings.ToLower(os.Getenv(portableEnv)) {
+	case "1", "true":
+		return true
+	}
+	_, err := os.Stat(filepath.Join(executableDir(), portableMarker))
+	return err == nil
+}
+
+// migrateLegacyConfig 旧版本把配置保存在程序目录或当前工作目录，首次使用新目录时移动过来。
+// 旧配置中可能有明文密码，迁移后不在原位置保留副本
+func migrateLegacyConfig(dir string) {
+	if _, err := os.Stat(filepath.Join(dir, configFileName)); err == nil {
+		return
+	}
+
+	cwd, _ := os.Getwd()
+	for _, legacyDir := range []string{executableDir(), cwd} {
+		if legacyDir == "" || sameDir(legacyDir, dir) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(legacyDir, configFileName)); err != nil {
+			continue
+		}
+
+		log.Printf("迁移旧配置: %s -> %s", legacyDir, dir)
+		for _, name := range []string{configFileName, secretFileName} {
+			err := moveFile(filepath.Join(legacyDir, name), filepath.Join(dir, name))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("迁移 %s 失败: %v", name, err)
+			}
+		}
+		return
+	}
+}
+
+// sameDir 判断两个路径是否指向同一目录
+func sameDir(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// moveFile 移动文件，不能直接重命名时（如跨磁盘）复制后删除原文件
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return os.Chmod(dst, 0o600)
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile 复制文件，目标文件仅当前用户可读写
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// applyGlobalFlags 解析 --config-dir 与 --portable，返回剩余参数
+func applyGlobalFlags(args []string) []string {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--portable" || arg == "-portable":
+			portableFlag = true
+		case arg == "--config-dir" || arg == "-config-dir":
+			if i+1 < len(args) {
+				configDirFlag = args[i+1]
+				i++
+			}
+		case strings.HasPrefix(arg, "--config-dir="):
+			configDirFlag = strings.TrimPrefix(arg, "--config-dir=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest
+}
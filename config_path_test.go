@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestResolveConfigDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("APPDATA", filepath.Join(home, "AppData", "Roaming"))
+
+	defaultDir := filepath.Join(home, ".config", "yzuautologin")
+	switch runtime.GOOS {
+	case "windows":
+		defaultDir = filepath.Join(home, "AppData", "Roaming", "YzuAutologin")
+	case "darwin":
+		defaultDir = filepath.Join(home, "Library", "Application Support", "YzuAutologin")
+	}
+
+	tests := []struct {
+		name     string
+		flag     string
+		env      string
+		portable bool
+		envPort  string
+		marker   bool
+		want     string
+	}{
+		{name: "默认使用用户配置目录", want: defaultDir},
+		{name: "命令行优先于环境变量与便携模式", flag: "/from/flag", env: "/from/env", portable: true, want: "/from/flag"},
+		{name: "环境变量优先于便携模式", env: "/from/env", portable: true, want: "/from/env"},
+		{name: "--portable 使用程序目录", portable: true, want: executableDir()},
+		{name: "环境变量启用便携模式", envPort: "TRUE", want: executableDir()},
+		{name: "环境变量为其他值时不启用便携模式", envPort: "yes", want: defaultDir},
+		{name: "程序目录下的 portable 文件启用便携模式", marker: true, want: executableDir()},
+	}
+
+	flag, portable := configDirFlag, portableFlag
+	t.Cleanup(func() { configDirFlag, portableFlag = flag, portable })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDirFlag, portableFlag = tt.flag, tt.portable
+			t.Setenv(configDirEnv, tt.env)
+			t.Setenv(portableEnv, tt.envPort)
+			if tt.marker {
+				marker := filepath.Join(executableDir(), portableMarker)
+				if err := os.WriteFile(marker, nil, 0o600); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { os.Remove(marker) })
+			}
+
+			if got := resolveConfigDir(); got != tt.want {
+				t.Errorf("期望 %s，实际为 %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyGlobalFlags(t *testing.T) {
+	flag, portable := configDirFlag, portableFlag
+	t.Cleanup(func() { configDirFlag, portableFlag = flag, portable })
+
+	tests := []struct {
+		args     []string
+		dir      string
+		portable bool
+		rest     []string
+	}{
+		{[]string{"login", "--profile", "电信"}, "", false, []string{"login", "--profile", "电信"}},
+		{[]string{"--config-dir", "/data", "status"}, "/data", false, []string{"status"}},
+		{[]string{"status", "--config-dir=/data", "--json"}, "/data", false, []string{"status", "--json"}},
+		{[]string{"-portable", "config", "get"}, "", true, []string{"config", "get"}},
+	}
+
+	for _, tt := range tests {
+		configDirFlag, portableFlag = "", false
+		rest := applyGlobalFlags(tt.args)
+		if configDirFlag != tt.dir || portableFlag != tt.portable || !slices.Equal(rest, tt.rest) {
+			t.Errorf("applyGlobalFlags(%q) = %q, %q, %v", tt.args, rest, configDirFlag, portableFlag)
+		}
+	}
+}
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	legacyDir, dir := t.TempDir(), t.TempDir()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(legacyDir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(cwd) })
+
+	legacy := `{"webindex":"http://10.0.0.1/eportal/index.jsp","countindex":"201900001","passwordindex":"plain-password"}`
+	if err := os.WriteFile(filepath.Join(legacyDir, configFileName), []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacyDir, secretFileName), []byte(`{"secrets":{}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	migrateLegacyConfig(dir)
+
+	// 旧位置不再保留明文密码
+	for _, name := range []string{configFileName, secretFileName} {
+		if content, err := os.ReadFile(filepath.Join(legacyDir, name)); err == nil {
+			t.Errorf("迁移后旧位置仍保留 %s: %s", name, content)
+		}
+	}
+	content, err := os.ReadFile(filepath.Join(dir, configFileName))
+	if err != nil || string(content) != legacy {
+		t.Errorf("配置没有迁移到新目录: %q %v", content, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, configFileName)); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("迁移后的配置文件权限过宽: %v", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(dir, secretFileName)); err != nil {
+		t.Errorf("凭据文件没有迁移到新目录: %v", err)
+	}
+}
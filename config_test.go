@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigMigratesV2ToProfiles(t *testing.T) {
+	v2 := `{"version":2,"webindex":"http://10.0.0.1/eportal/index.jsp?wlanuserip=10.0.0.2",` +
+		`"countindex":"201900002","passwordindex":"plain-password","operatorindex":"d",` +
+		`"autostartindex":true,"driver":"http","watchdog":false}`
+	if err := os.WriteFile(configFilePath(), []byte(v2), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+	if config.DefaultProfile != defaultProfileName || len(config.Profiles) != 1 {
+		t.Fatalf("迁移后的账号配置不正确: %v", config)
+	}
+	if config.Countindex != "201900002" || config.Passwordindex != "plain-password" || config.Operatorindex != "电信" {
+		t.Errorf("默认账号没有展开: %v", config)
+	}
+	if !config.Autostartindex || config.Driver != "http" || config.Watchdog {
+		t.Errorf("其他配置项不正确: %v", config)
+	}
+
+	content, err := os.ReadFile(configFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "plain-password") {
+		t.Errorf("迁移后配置文件中仍有明文密码: %s", content)
+	}
+	var saved map[string]interface{}
+	if err := json.Unmarshal(content, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved["version"] != float64(currentConfigVersion) || saved["countindex"] != nil {
+		t.Errorf("迁移后的配置文件不正确: %s", content)
+	}
+}
+
+func TestLoginWithProfile(t *testing.T) {
+	portal := newMockPortal(t, scenarioSuccess)
+	config := saveTestConfig(t, portal, "http", "wrong-password")
+	config.Profiles = append(config.Profiles, Profile{
+		Name:          "电信",
+		Webindex:      portal.LoginURL(),
+		Countindex:    "201900003",
+		Passwordindex: "correct-password",
+		Operatorindex: "telecom",
+	})
+	if err := SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	result, err := app.LoginWithProfile("电信")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if result.Profile != "电信" {
+		t.Errorf("登录结果中的账号为 %q", result.Profile)
+	}
+	got := portal.Submissions()[0]
+	if got.UserID != "201900003" || got.Password != "correct-password" || got.Service != "电信" {
+		t.Errorf("提交的数据不正确: %+v", got)
+	}
+
+	// 指定账号登录不改变默认账号
+	config, err = LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DefaultProfile != defaultProfileName || config.Countindex != "201900001" {
+		t.Errorf("默认账号被修改: %v", config)
+	}
+
+	config, err = app.SwitchProfile("电信")
+	if err != nil {
+		t.Fatalf("切换账号失败: %v", err)
+	}
+	if config.Countindex != "201900003" || config.Passwordindex != "correct-password" {
+		t.Errorf("切换后的账号不正确: %v", config)
+	}
+	if _, err := app.LoginWithProfile("不存在"); err == nil {
+		t.Error("不存在的账号应返回错误")
+	}
+}
+
+func TestRemoveProfileDeletesPassword(t *testing.T) {
+	config := saveTestConfig(t, newMockPortal(t, scenarioSuccess), "http", "correct-password")
+	config.Profiles = append(config.Profiles,
+		Profile{Name: "电信", Webindex: config.Webindex, Countindex: "201900005", Passwordindex: "telecom-password", Operatorindex: "电信"},
+		Profile{Name: "联通", Webindex: config.Webindex, Countindex: "201900001", Operatorindex: "联通"},
+	)
+	if err := SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	if err := app.RemoveProfile("电信"); err != nil {
+		t.Fatalf("删除账号失败: %v", err)
+	}
+	if _, err := secretStore().Get(passwordSecretKey("201900005")); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("删除账号后密码仍在凭据存储中: %v", err)
+	}
+
+	// 其他账号仍在使用同一学号时保留密码
+	if err := app.RemoveProfile("联通"); err != nil {
+		t.Fatalf("删除账号失败: %v", err)
+	}
+	if password, err := secretStore().Get(passwordSecretKey("201900001")); err != nil || password != "correct-password" {
+		t.Errorf("默认账号的密码被删除: %q %v", password, err)
+	}
+
+	if err := app.RemoveProfile(defaultProfileName); err == nil {
+		t.Error("不能删除默认账号")
+	}
+	if err := app.RemoveProfile("不存在"); err == nil {
+		t.Error("不存在的账号应返回错误")
+	}
+}
+
+func TestRemoveProfileUsedByFailover(t *testing.T) {
+	config := saveTestConfig(t, newMockPortal(t, scenarioSuccess), "http", "correct-password")
+	config.Profiles = append(config.Profiles,
+		Profile{Name: "张三", Webindex: config.Webindex, Countindex: "201900006", Passwordindex: "other-password", Operatorindex: "联通"},
+	)
+	config.Failover = parseFailoverSteps("默认:电信,张三,张三:移动")
+	if err := SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	// 拒绝删除并指出引用该账号的故障转移步骤
+	code, _, stderr := runTestCLI(t, "profile", "remove", "张三")
+	if code != exitFailure || !strings.Contains(stderr, "张三,张三:移动") {
+		t.Errorf("删除故障转移中的账号应失败并给出原因: %d %q", code, stderr)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.profileIndex("张三") < 0 {
+		t.Error("故障转移中使用的账号被删除")
+	}
+
+	// 从故障转移中移除后可以删除
+	if code, _, stderr := runTestCLI(t, "config", "set", "failover", "默认:电信"); code != exitOK {
+		t.Fatalf("修改故障转移失败: %s", stderr)
+	}
+	if code, _, stderr := runTestCLI(t, "profile", "remove", "张三"); code != exitOK {
+		t.Errorf("删除账号失败: %s", stderr)
+	}
+}
+
+func TestLoginFailover(t *testing.T) {
+	tests := []struct {
+		name         string
+		scenario     mockScenario
+		password     string
+		failover     string
+		wantProfile  string
+		wantOperator string
+		wantFallback int
+		wantReasons  []string
+	}{
+		{
+			name:         "运营商不可用时换运营商",
+			scenario:     scenarioOperatorDown,
+			password:     "correct-password",
+			failover:     "默认:电信,张三",
+			wantProfile:  defaultProfileName,
+			wantOperator: "电信",
+			wantFallback: 1,
+			wantReasons:  []string{"operator_unavailable", ""},
+		},
+		{
+			// 第一个备用步骤与默认账号重复，序号仍按配置的故障转移列表计算
+			name:         "跳过重复步骤后的序号",
+			scenario:     scenarioOperatorDown,
+			password:     "correct-password",
+			failover:     "默认,默认:电信",
+			wantProfile:  defaultProfileName,
+			wantOperator: "电信",
+			wantFallback: 2,
+			wantReasons:  []string{"operator_unavailable", ""},
+		},
+		{
+			name:         "密码错误时跳过同一账号的其他运营商",
+			scenario:     scenarioSuccess,
+			password:     "wrong-password",
+			failover:     "默认:电信,张三",
+			wantProfile:  "张三",
+			wantOperator: "联通",
+			wantFallback: 2,
+			wantReasons:  []string{"bad_credentials", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portal := newMockPortal(t, tt.scenario)
+			config := saveTestConfig(t, portal, "http", tt.password)
+			config.Profiles = append(config.Profiles, Profile{
+				Name:          "张三",
+				Webindex:      portal.LoginURL(),
+				Countindex:    "201900004",
+				Passwordindex: "correct-password",
+				Operatorindex: "联通",
+			})
+			config.Failover = parseFailoverSteps(tt.failover)
+			if err := SaveConfig(config); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := NewApp().Loginyzu()
+			if err != nil {
+				t.Fatalf("登录失败: %v (尝试: %+v)", err, result.Attempts)
+			}
+			if result.Profile != tt.wantProfile || result.Operator != tt.wantOperator || result.Fallback != tt.wantFallback {
+				t.Errorf("成功的步骤为 %s:%s (序号 %d)，期望 %s:%s (序号 %d)",
+					result.Profile, result.Operator, result.Fallback, tt.wantProfile, tt.wantOperator, tt.wantFallback)
+			}
+			if len(result.Attempts) != len(tt.wantReasons) {
+				t.Fatalf("尝试记录为 %+v", result.Attempts)
+			}
+			for i, attempt := range result.Attempts {
+				if attempt.Reason != tt.wantReasons[i] {
+					t.Errorf("第 %d 次尝试的原因为 %q，期望 %q", i+1, attempt.Reason, tt.wantReasons[i])
+				}
+			}
+		})
+	}
+}
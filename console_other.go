@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// attachParentConsole 非 Windows 平台的程序本身就在终端中运行，无需处理
+func attachParentConsole() {}
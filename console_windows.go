@@ -0,0 +1,22 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// attachParentConsole 界面程序默认没有控制台，命令行模式下附加到父进程的控制台以便输出
+func attachParentConsole() {
+	const attachParentProcess = ^uint32(0) // ATTACH_PARENT_PROCESS
+
+	proc := windows.NewLazySystemDLL("kernel32.dll").NewProc("AttachConsole")
+	if ret, _, _ := proc.Call(uintptr(attachParentProcess)); ret == 0 {
+		return
+	}
+
+	if out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+		os.Stdout = out
+		os.Stderr = out
+	}
+}
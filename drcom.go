@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Dr.COM 网页认证表单中的固定字段
+const (
+	drcomUsernameField = "DDDDD"
+	drcomPasswordField = "upass"
+)
+
+// drcomDefaultFields 页面脚本提交时补上的字段，页面中已有的值优先
+var drcomDefaultFields = map[string]string{
+	"0MKKey": "123456",
+	"R1":     "0",
+	"R2":     "",
+	"R3":     "0",
+	"R6":     "0",
+	"para":   "00",
+}
+
+var (
+	// drcomMsgPattern 结果页面脚本中的 Msg=01 形式的结果代码
+	drcomMsgPattern = regexp.MustCompile(`\bMsg\s*=\s*'?(\d+)'?`)
+	// drcomMsgaPattern 结果页面脚本中的附加错误信息
+	drcomMsgaPattern = regexp.MustCompile(`\bmsga\s*=\s*'([^']*)'`)
+	// 页面脚本中的密码加密参数：ps=1 时密码以 MD5 提交
+	drcomPSPattern   = regexp.MustCompile(`\bps\s*=\s*(\d)`)
+	drcomPIDPattern  = regexp.MustCompile(`\bpid\s*=\s*'(\d+)'`)
+	drcomCalgPattern = regexp.MustCompile(`\bcalg\s*=\s*'([^']*)'`)
+)
+
+// drcomResult Dr.COM 结果代码的含义，Kind 为空表示无法归类
+type drcomResult struct {
+	Kind    error
+	Message string
+}
+
+// drcomResults 结果代码与提示，取自 Dr.COM 页面脚本
+var drcomResults = map[string]drcomResult{
+	"01": {ErrBadCredentials, "账号或密码不对，请重新输入"},
+	// 账号正被其他设备使用，本机并未在线
+	"02": {ErrDeviceLimit, "该账号正在使用中，请与网管联系"},
+	"03": {nil, "本账号只能在指定地址使用"},
+	"04": {ErrAccountSuspended, "本账号费用超支或时长流量超过限制"},
+	"05": {ErrAccountSuspended, "本账号暂停使用"},
+	"11": {nil, "本账号只能在指定的 MAC 地址使用"},
+	"14": {nil, "注销成功"},
+	"15": {nil, "登录成功"},
+}
+
+// drcomMsgaMessages msga 中常见的英文错误信息
+var drcomMsgaMessages = map[string]string{
+	"error0":          "本 IP 不允许 Web 方式登录",
+	"error1":          "本账号不允许 Web 方式登录",
+	"userid error1":   "账号不存在",
+	"userid error2":   "密码错误",
+	"userid error3":   "密码错误",
+	"ldap auth error": "密码错误",
+}
+
+// drcomSuccessKeywords 认证成功页面的文字
+var drcomSuccessKeywords = []string{"您已经成功登录", "认证成功页", "登录成功窗"}
+
+// drcomForm Dr.COM 网页认证：账号密码字段名固定，并补全 0MKKey 与 R1–R6 等字段
+type drcomForm struct{}
+
+func init() {
+	RegisterLoginDriver("drcom", func() LoginDriver { return &formDriver{name: "drcom", variant: drcomForm{}} })
+}
+
+func (drcomForm) prepare(form *LoginForm, config *Config) error {
+	if form.Values.Has(drcomUsernameField) {
+		form.UsernameField = drcomUsernameField
+	}
+	if form.Values.Has(drcomPasswordField) {
+		form.PasswordField = drcomPasswordField
+	}
+	if form.UsernameField == "" {
+		return fmt.Errorf("登录表单中找不到用户名输入框")
+	}
+
+	for name, value := range drcomDefaultFields {
+		if !form.Values.Has(name) {
+			form.Values.Set(name, value)
+		}
+	}
+
+	form.Values.Set(form.UsernameField, config.Countindex)
+	password := config.Passwordindex
+	if pid, calg, ok := drcomMD5Params(form.source); ok {
+		// 与页面脚本一致：md5(pid + 密码 + calg) 后再拼接 calg 与 pid
+		sum := md5.Sum([]byte(pid + password + calg))
+		password = hex.EncodeToString(sum[:]) + calg + pid
+		form.Values.Set("R2", "1")
+	}
+	form.Values.Set(form.PasswordField, password)
+	return nil
+}
+
+// drcomMD5Params 读取页面脚本中的密码加密参数，ps 不为 1 时密码以明文提交
+func drcomMD5Params(source []byte) (pid, calg string, ok bool) {
+	ps := drcomPSPattern.FindSubmatch(source)
+	if ps == nil || string(ps[1]) != "1" {
+		return "", "", false
+	}
+	pid, calg = "1", "12345678"
+	if match := drcomPIDPattern.FindSubmatch(source); match != nil {
+		pid = string(match[1])
+	}
+	if match := drcomCalgPattern.FindSubmatch(source); match != nil {
+		calg = string(match[1])
+	}
+	return pid, calg, true
+}
+
+// drcomMessage 读取结果页面中的结果代码与提示，没有结果代码时 code 为空
+func drcomMessage(body []byte) (code string, result drcomResult) {
+	match := drcomMsgPattern.FindSubmatch(body)
+	if match == nil {
+		return "", drcomResult{}
+	}
+	code = string(match[1])
+	if len(code) == 1 {
+		code = "0" + code
+	}
+	result, ok := drcomResults[code]
+	if !ok {
+		result = drcomResult{Message: "结果代码 " + code}
+	}
+
+	if msga := drcomMsgaPattern.FindSubmatch(body); msga != nil && len(msga[1]) > 0 {
+		text := strings.TrimSpace(string(msga[1]))
+		if message, ok := drcomMsgaMessages[strings.ToLower(text)]; ok {
+			text = message
+		}
+		// msga 说明了具体原因时以其为准，由提示文字重新归类
+		result = drcomResult{Message: text}
+	}
+	return code, result
+}
+
+func (drcomForm) verify(ctx context.Context, result *LoginResult, finalURL string, body []byte) error {
+	code, message := drcomMessage(body)
+	if code != "" {
+		result.Message = message.Message
+	}
+
+	switch {
+	case code == "15":
+		result.Outcome = LoginOutcomeSuccess
+		return nil
+	case code != "" && code != "14":
+		if message.Kind == nil {
+			return portalFailure(message.Message)
+		}
+		return &PortalError{Kind: message.Kind, Message: message.Message}
+	}
+
+	text := string(body)
+	for _, keyword := range drcomSuccessKeywords {
+		if strings.Contains(text, keyword) {
+			result.Outcome = LoginOutcomeSuccess
+			result.Message = keyword
+			return nil
+		}
+	}
+	return genericForm{}.verify(ctx, result, finalURL, body)
+}
+
+func (drcomForm) logout(ctx context.Context, client *FormClient, config *Config, result *LoginResult) error {
+	log.Println("通过 Dr.COM 注销页面注销...")
+	base, err := url.Parse(config.Webindex)
+	if err != nil {
+		return fmt.Errorf("解析登录链接失败: %w", err)
+	}
+	logoutURL := base.ResolveReference(&url.URL{Path: "/F.htm"}).String()
+
+	var finalURL string
+	var body []byte
+	err = result.runStep(ctx, "提交注销", func() (err error) {
+		finalURL, body, err = fetchPortalPage(ctx, client.httpClient, logoutURL)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	result.FinalURL = finalURL
+
+	code, message := drcomMessage(body)
+	result.Message = message.Message
+	if code == "14" || strings.Contains(string(body), "注销成功") {
+		result.Outcome = LoginOutcomeSuccess
+		return nil
+	}
+	result.Outcome = LoginOutcomeUnverified
+	return ErrLogoutUnverified
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// FailoverStep 故障转移策略中的一步：使用指定账号登录，Operator 不为空时替换该账号的运营商
+type FailoverStep struct {
+	Profile  string `json:"profile"`
+	Operator string `json:"operator,omitempty"`
+}
+
+// LoginAttempt 故障转移过程中一次登录尝试的结果
+type LoginAttempt struct {
+	Profile    string       `json:"profile"`
+	Operator   string       `json:"operator"`
+	Outcome    LoginOutcome `json:"outcome"`
+	Reason     string       `json:"reason,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	DurationMs int64        `json:"duration_ms"`
+}
+
+// String 以 "账号:运营商" 的形式显示，运营商为空时只显示账号
+func (s FailoverStep) String() string {
+	if s.Operator == "" {
+		return s.Profile
+	}
+	return s.Profile + ":" + s.Operator
+}
+
+// parseFailoverSteps 解析命令行中以逗号分隔的 "账号[:运营商]" 列表
+func parseFailoverSteps(value string) []FailoverStep {
+	var steps []FailoverStep
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		profile, operator, _ := strings.Cut(item, ":")
+		steps = append(steps, FailoverStep{Profile: strings.TrimSpace(profile), Operator: strings.TrimSpace(operator)})
+	}
+	return steps
+}
+
+// formatFailoverSteps 将故障转移策略格式化为 parseFailoverSteps 可解析的字符串
+func formatFailoverSteps(steps []FailoverStep) string {
+	items := make([]string, len(steps))
+	for i, step := range steps {
+		items[i] = step.String()
+	}
+	return strings.Join(items, ",")
+}
+
+// plannedFailoverStep 尝试顺序中的一步，fallback 为该步在 Failover 中从 1 开始的序号，默认账号为 0
+type plannedFailoverStep struct {
+	FailoverStep
+	fallback int
+}
+
+// failoverPlan 返回完整的尝试顺序：先使用默认账号，再依次尝试 Failover 中的各步。
+// 未指定运营商的步骤使用账号自己的运营商，重复的步骤只尝试一次
+func (c *Config) failoverPlan() []plannedFailoverStep {
+	plan := []plannedFailoverStep{{FailoverStep: FailoverStep{Profile: c.DefaultProfile, Operator: c.Operatorindex}}}
+	for i, step := range c.Failover {
+		if j := c.profileIndex(step.Profile); j >= 0 && step.Operator == "" {
+			step.Operator = c.Profiles[j].Operatorindex
+		}
+
+		duplicate := false
+		for _, planned := range plan {
+			if planned.FailoverStep == step {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			plan = append(plan, plannedFailoverStep{FailoverStep: step, fallback: i + 1})
+		}
+	}
+	return plan
+}
+
+// shouldFailover 判断登录失败后是否继续尝试下一步。
+// 账号已在线说明网络可用；无法确认结果时可能已经登录，继续尝试会把刚登录的会话顶掉
+func shouldFailover(err error) bool {
+	return !errors.Is(err, ErrAlreadyOnline) && !errors.Is(err, ErrLoginUnverified)
+}
+
+// isAccountError 判断错误是否与账号本身有关，换运营商也不会成功
+func isAccountError(err error) bool {
+	return errors.Is(err, ErrBadCredentials) || errors.Is(err, ErrAccountSuspended)
+}
+
+// loginWithFailover 按故障转移策略依次登录，第一次成功或 ctx 结束即停止。
+// 返回成功或最后一次尝试的结果，Attempts 记录每次尝试，成功时 Fallback 为该步在 Failover 中的序号
+func loginWithFailover(ctx context.Context, config *Config) (*LoginResult, error) {
+	plan := config.failoverPlan()
+	start := time.Now()
+
+	var (
+		result   *LoginResult
+		err      error
+		attempts []LoginAttempt
+		skipped  = map[string]bool{}
+	)
+	for i, step := range plan {
+		if skipped[step.Profile] {
+			log.Printf("账号 %s 已因账号问题失败，跳过 %s", step.Profile, step)
+			continue
+		}
+		if i > 0 {
+			log.Printf("故障转移: 尝试 %s", step)
+		}
+
+		result, err = loginFailoverStep(ctx, config, step.FailoverStep)
+		attempt := LoginAttempt{Profile: step.Profile, Operator: step.Operator, Outcome: LoginOutcomeFailed}
+		if result != nil {
+			attempt.Outcome = result.Outcome
+			attempt.DurationMs = result.DurationMs
+		}
+		if err != nil {
+			attempt.Error = err.Error()
+			attempt.Reason = portalErrorCode(err)
+		}
+		attempts = append(attempts, attempt)
+
+		if err == nil {
+			result.Fallback = step.fallback
+			break
+		}
+		if !shouldFailover(err) || ctx.Err() != nil {
+			break
+		}
+		if isAccountError(err) {
+			skipped[step.Profile] = true
+		}
+		log.Printf("%s 登录失败: %v", step, err)
+	}
+
+	if result == nil {
+		result = newLoginResult("", config)
+		result.Error = err.Error()
+	}
+	if len(plan) > 1 {
+		result.Attempts = attempts
+		result.DurationMs = time.Since(start).Milliseconds()
+	}
+	return result, err
+}
+
+// loginFailoverStep 使用故障转移策略中的一步登录
+func loginFailoverStep(ctx context.Context, config *Config, step FailoverStep) (*LoginResult, error) {
+	view, err := config.ForProfile(step.Profile)
+	if err != nil {
+		return nil, err
+	}
+	if step.Operator != "" {
+		view.Operatorindex = step.Operator
+	}
+	if view.Countindex == "" {
+		return nil, fmt.Errorf("账号 %s 未填写账号", step.Profile)
+	}
+
+	driver, err := NewLoginDriver(view.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return driver.Login(ctx, view)
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// PortalVendor 认证系统厂商
+type PortalVendor string
+
+const (
+	VendorUnknown PortalVendor = "unknown"
+	VendorRuijie  PortalVendor = "ruijie"
+	VendorSrun    PortalVendor = "srun"
+	VendorDrcom   PortalVendor = "drcom"
+	VendorH3C     PortalVendor = "h3c"
+)
+
+// PortalFingerprint 认证页面的识别结果
+type PortalFingerprint struct {
+	Vendor PortalVendor `json:"vendor"`
+	// Name 厂商与产品的显示名称
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	URL     string `json:"url"`
+	Title   string `json:"title,omitempty"`
+	// Evidence 命中的特征，便于排查识别错误
+	Evidence []string `json:"evidence,omitempty"`
+	Score    int      `json:"score"`
+}
+
+// portalVersion 从认证地址与页面源码中提取版本，Version 为空时取第一个分组
+type portalVersion struct {
+	Pattern *regexp.Regexp
+	Version string
+}
+
+// portalSignature 一种认证系统的特征，均为小写
+type portalSignature struct {
+	Vendor   PortalVendor
+	Name     string
+	URLs     []string
+	Titles   []string
+	Scripts  []string
+	Fields   []string
+	Keywords []string
+	Versions []portalVersion
+}
+
+// 各类特征的权重：地址与脚本名最可靠，页面标题常被学校改掉
+const (
+	urlSignalWeight     = 3
+	scriptSignalWeight  = 3
+	fieldSignalWeight   = 2
+	keywordSignalWeight = 2
+	titleSignalWeight   = 1
+
+	// minPortalScore 低于该分数时视为无法识别
+	minPortalScore = 3
+)
+
+// portalSignatures 已知的认证系统特征
+var portalSignatures = []portalSignature{
+	{
+		Vendor:   VendorRuijie,
+		Name:     "锐捷 ePortal",
+		URLs:     []string{"/eportal/"},
+		Titles:   []string{"ruijie", "锐捷"},
+		Scripts:  []string{"authinterface", "eportal"},
+		Fields:   []string{"_service_", "selectdisname", "loginlink", "pwd_tip", "username_tip"},
+		Keywords: []string{"interface.do", "ruijie", "getonlineuserinfo"},
+		Versions: []portalVersion{
+			{Pattern: regexp.MustCompile(`(?i)eportal\s*v(\d+(?:\.\d+)+)`)},
+		},
+	},
+	{
+		Vendor:   VendorSrun,
+		Name:     "深澜 Srun",
+		URLs:     []string{"srun_portal"},
+		Titles:   []string{"srun", "深澜"},
+		Scripts:  []string{"srun", "hashes", "base64.js"},
+		Fields:   []string{"ac_id", "login-account", "user_ip"},
+		Keywords: []string{"get_challenge", "srun_portal", "xencode"},
+		Versions: []portalVersion{
+			{Pattern: regexp.MustCompile(`get_challenge`), Version: "challenge"},
+			{Pattern: regexp.MustCompile(`srun_portal_pc\.php`), Version: "php"},
+		},
+	},
+	{
+		Vendor:   VendorDrcom,
+		Name:     "Dr.COM",
+		URLs:     []string{"drcom", "/a70.htm", "/0.htm"},
+		Titles:   []string{"dr.com", "drcom", "上网登录页"},
+		Scripts:  []string{"drcom", "a41.js", "a40.js", "a42.js"},
+		Fields:   []string{"0mkkey", "ddddd", "upass", "r6"},
+		Keywords: []string{"dr.com", "drcom", "0mkkey"},
+		Versions: []portalVersion{
+			{Pattern: regexp.MustCompile(`/eportal/portal/login`), Version: "ePortal"},
+			{Pattern: regexp.MustCompile(`\b(a\d{2})\.js`)},
+		},
+	},
+	{
+		Vendor:   VendorH3C,
+		Name:     "H3C iMC",
+		URLs:     []string{"portalservlet", "/portal/pws", "/portal/index_default.jsp"},
+		Titles:   []string{"h3c", "imc", "inode"},
+		Scripts:  []string{"h3c", "portal_util"},
+		Fields:   []string{"useridtemp", "passwordtemp", "portalpagetype"},
+		Keywords: []string{"h3c", "pws?t=li", "portalservlet", "inode"},
+		Versions: []portalVersion{
+			{Pattern: regexp.MustCompile(`(?i)imc\s*(?:plat\s*)?(\d+\.\d+)`)},
+		},
+	},
+}
+
+// portalPage 从认证页面中提取的特征
+type portalPage struct {
+	url     string
+	title   string
+	scripts []string
+	fields  []string
+	source  string
+}
+
+// parsePortalPage 读取页面标题、脚本地址与所有元素的 id 和 name
+func parsePortalPage(pageURL string, body []byte) portalPage {
+	page := portalPage{
+		url:    strings.ToLower(pageURL),
+		source: strings.ToLower(string(body)),
+	}
+
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return page
+	}
+
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			switch node.Data {
+			case "title":
+				if page.title == "" {
+					page.title = htmlText(node)
+				}
+			case "script":
+				if src := htmlAttr(node, "src"); src != "" {
+					page.scripts = append(page.scripts, strings.ToLower(src))
+				}
+			}
+			for _, key := range []string{"id", "name"} {
+				if value := htmlAttr(node, key); value != "" {
+					page.fields = append(page.fields, strings.ToLower(value))
+				}
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	return page
+}
+
+// match 计算页面与特征的匹配分数
+func (s *portalSignature) match(page portalPage) (int, []string) {
+	score := 0
+	var evidence []string
+
+	hit := func(weight int, kind, signal string) {
+		score += weight
+		evidence = append(evidence, kind+":"+signal)
+	}
+	for _, signal := range s.URLs {
+		if strings.Contains(page.url, signal) {
+			hit(urlSignalWeight, "url", signal)
+		}
+	}
+	title := strings.ToLower(page.title)
+	for _, signal := range s.Titles {
+		if strings.Contains(title, signal) {
+			hit(titleSignalWeight, "title", signal)
+		}
+	}
+	for _, signal := range s.Scripts {
+		for _, script := range page.scripts {
+			if strings.Contains(script, signal) {
+				hit(scriptSignalWeight, "script", signal)
+				break
+			}
+		}
+	}
+	for _, signal := range s.Fields {
+		for _, field := range page.fields {
+			if strings.HasPrefix(field, signal) {
+				hit(fieldSignalWeight, "field", signal)
+				break
+			}
+		}
+	}
+	for _, signal := range s.Keywords {
+		if strings.Contains(page.source, signal) {
+			hit(keywordSignalWeight, "keyword", signal)
+		}
+	}
+
+	return score, evidence
+}
+
+// version 从地址与页面源码中提取版本号
+func (s *portalSignature) version(page portalPage) string {
+	for _, rule := range s.Versions {
+		for _, text := range []string{page.url, page.source} {
+			match := rule.Pattern.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			if rule.Version != "" {
+				return rule.Version
+			}
+			if len(match) > 1 {
+				return match[1]
+			}
+		}
+	}
+	return ""
+}
+
+// fingerprintPortal 根据最终地址、页面标题、脚本名与表单结构识别认证系统，分数最高者胜出
+func fingerprintPortal(pageURL string, body []byte) *PortalFingerprint {
+	page := parsePortalPage(pageURL, body)
+	result := &PortalFingerprint{Vendor: VendorUnknown, Name: "未知", URL: pageURL, Title: page.title}
+
+	for i := range portalSignatures {
+		signature := &portalSignatures[i]
+		score, evidence := signature.match(page)
+		if score < minPortalScore || score <= result.Score {
+			continue
+		}
+		result.Vendor = signature.Vendor
+		result.Name = signature.Name
+		result.Score = score
+		result.Evidence = evidence
+		result.Version = signature.version(page)
+	}
+	sort.Strings(result.Evidence)
+	return result
+}
+
+var (
+	portalCacheMu sync.Mutex
+	// portalCache 按认证地址缓存已识别的认证系统，避免每次登录都重新请求页面
+	portalCache = map[string]*PortalFingerprint{}
+)
+
+// cachedPortalFingerprint 返回已缓存的识别结果，不发起请求
+func cachedPortalFingerprint(portalURL string) *PortalFingerprint {
+	portalCacheMu.Lock()
+	defer portalCacheMu.Unlock()
+	return portalCache[portalURL]
+}
+
+// DetectPortal 请求认证页面并识别认证系统，识别成功的结果按地址缓存。
+// 页面只有脚本跳转时跟随一次跳转
+func DetectPortal(ctx context.Context, portalURL string, timeout time.Duration) (*PortalFingerprint, error) {
+	if fingerprint := cachedPortalFingerprint(portalURL); fingerprint != nil {
+		return fingerprint, nil
+	}
+
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: portalTransport,
+	}
+
+	target := portalURL
+	var fingerprint *PortalFingerprint
+	for hop := 0; hop < 2; hop++ {
+		finalURL, body, err := fetchPortalPage(ctx, client, target)
+		if err != nil {
+			return nil, err
+		}
+		fingerprint = fingerprintPortal(finalURL, body)
+		if fingerprint.Vendor != VendorUnknown {
+			break
+		}
+
+		match := portalRedirectPattern.FindSubmatch(body)
+		if match == nil {
+			break
+		}
+		next, err := url.Parse(string(match[1]))
+		if err != nil {
+			break
+		}
+		base, _ := url.Parse(finalURL)
+		target = base.ResolveReference(next).String()
+	}
+
+	if fingerprint.Vendor != VendorUnknown {
+		log.Printf("识别到认证系统: %s %s (%s)", fingerprint.Name, fingerprint.Version, strings.Join(fingerprint.Evidence, ", "))
+		portalCacheMu.Lock()
+		portalCache[portalURL] = fingerprint
+		portalCacheMu.Unlock()
+	}
+	return fingerprint, nil
+}
+
+// fetchPortalPage 请求页面，返回跟随重定向后的地址与页面内容
+func fetchPortalPage(ctx context.Context, client *http.Client, pageURL string) (string, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("访问认证页面失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256*1024))
+	if err != nil {
+		return "", nil, fmt.Errorf("读取认证页面失败: %w", err)
+	}
+	return resp.Request.URL.String(), body, nil
+}
+
+// vendorDrivers 识别出厂商后 auto 驱动使用的登录驱动。
+// 锐捷与无法识别的页面沿用 HTTP 登录失败后回退浏览器模拟的方式，其他未列出的厂商直接使用浏览器按登录脚本登录
+var vendorDrivers = map[PortalVendor]string{
+	VendorSrun:  "srun",
+	VendorDrcom: "drcom",
+}
+
+// vendorDriverName 返回识别结果对应的登录驱动，空字符串表示沿用 auto 驱动的默认方式
+func vendorDriverName(fingerprint *PortalFingerprint) string {
+	if fingerprint == nil || fingerprint.Vendor == VendorUnknown || fingerprint.Vendor == VendorRuijie {
+		return ""
+	}
+	if name, ok := vendorDrivers[fingerprint.Vendor]; ok {
+		return name
+	}
+	return "rod"
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestFingerprintPortal(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		body        string
+		wantVendor  PortalVendor
+		wantVersion string
+	}{
+		{
+			name:       "锐捷",
+			url:        "http://10.0.0.1/eportal/index.jsp?wlanuserip=10.20.30.40&nasip=10.0.0.2",
+			body:       mockIndexPage,
+			wantVendor: VendorRuijie,
+		},
+		{
+			name: "深澜",
+			url:  "http://10.0.0.2/srun_portal_pc?ac_id=1&theme=basic",
+			body: `<html><head><title>校园网认证</title>
+				<script src="/static/js/jquery.srun.portal.js"></script></head>
+				<body><input id="username"><input id="password" type="password"><input type="hidden" id="ac_id" value="1">
+				<script>$.get('/cgi-bin/get_challenge', {username: u})</script></body></html>`,
+			wantVendor:  VendorSrun,
+			wantVersion: "challenge",
+		},
+		{
+			// wlanuserip、nasip 是各厂商通用的重定向参数，不能作为锐捷的特征
+			name: "带通用重定向参数的深澜",
+			url:  "http://10.0.0.2/srun_portal_pc?ac_id=1&wlanuserip=10.20.30.40&nasip=10.0.0.1",
+			body: `<html><head><title>校园网认证</title></head>
+				<body><input id="username"><input id="password" type="password"></body></html>`,
+			wantVendor: VendorSrun,
+		},
+		{
+			name: "Dr.COM",
+			url:  "http://10.0.0.3/",
+			body: `<html><head><title>上网登录页</title><script src="a41.js"></script></head>
+				<body><form name="f1" method="post" action="">
+				<input name="DDDDD"><input name="upass" type="password"><input type="hidden" name="0MKKey" value="123456">
+				<input type="hidden" name="R6" value="0"></form></body></html>`,
+			wantVendor:  VendorDrcom,
+			wantVersion: "a41",
+		},
+		{
+			name: "H3C",
+			url:  "http://10.0.0.4/portal/index_default.jsp",
+			body: `<html><head><title>iNode Portal</title><script src="/portal/js/portal_util.js"></script></head>
+				<body><form action="/portal/pws?t=li"><input name="userIdTemp"><input name="passwordTemp" type="password"></form>
+				<div>Powered by H3C iMC 7.3</div></body></html>`,
+			wantVendor:  VendorH3C,
+			wantVersion: "7.3",
+		},
+		{
+			name:       "只有通用重定向参数",
+			url:        "http://10.0.0.5/login?wlanuserip=10.20.30.40&nasip=10.0.0.1",
+			body:       `<html><head><title>校园网认证</title></head><body><input name="username"></body></html>`,
+			wantVendor: VendorUnknown,
+		},
+		{
+			// /cgi-bin/、ac_id 参数在其他认证系统中也很常见，不能单独作为深澜的特征
+			name:       "通用 cgi-bin 登录页面",
+			url:        "http://10.0.0.5/cgi-bin/login.cgi?ac_id=1",
+			body:       `<html><head><title>校园网认证</title></head><body><form method="post"><input name="username"><input name="password" type="password"></form></body></html>`,
+			wantVendor: VendorUnknown,
+		},
+		{
+			name:       "普通页面",
+			url:        "http://www.example.com/",
+			body:       `<html><head><title>Example</title></head><body><a href="/login">登录</a></body></html>`,
+			wantVendor: VendorUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprint := fingerprintPortal(tt.url, []byte(tt.body))
+			if fingerprint.Vendor != tt.wantVendor {
+				t.Fatalf("识别为 %s (分数 %d, 特征 %v)，期望 %s", fingerprint.Vendor, fingerprint.Score, fingerprint.Evidence, tt.wantVendor)
+			}
+			if fingerprint.Version != tt.wantVersion {
+				t.Errorf("版本为 %q，期望 %q", fingerprint.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestDetectPortalWithMock(t *testing.T) {
+	portal := newMockPortal(t, scenarioSuccess)
+
+	fingerprint, err := DetectPortal(context.Background(), portal.LoginURL(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("识别认证系统失败: %v", err)
+	}
+	if fingerprint.Vendor != VendorRuijie {
+		t.Errorf("识别为 %s，期望 %s", fingerprint.Vendor, VendorRuijie)
+	}
+	if cached := cachedPortalFingerprint(portal.LoginURL()); cached != fingerprint {
+		t.Errorf("识别结果没有缓存")
+	}
+	if vendorDriverName(fingerprint) != "" {
+		t.Errorf("锐捷应沿用 auto 驱动的默认方式")
+	}
+}
+
+func TestSelectRecipeByVendor(t *testing.T) {
+	writeTestRecipe(t, "h3c.json", `{"name": "h3c", "vendor": "h3c", "steps": [{"name": "等待", "action": "wait"}]}`)
+
+	config := DefaultConfig()
+	config.Webindex = "http://10.0.0.4/portal/index_default.jsp"
+
+	recipe, err := selectRecipe(config, VendorH3C)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe.Name != "h3c" {
+		t.Errorf("选择了 %s，期望 h3c", recipe.Name)
+	}
+
+	recipe, err = selectRecipe(config, VendorSrun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe.Name != defaultRecipeName {
+		t.Errorf("没有对应脚本时选择了 %s，期望 %s", recipe.Name, defaultRecipeName)
+	}
+}
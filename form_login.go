@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// formField 登录表单中的一个输入框
+type formField struct {
+	Tag   string
+	Type  string
+	Name  string
+	attrs map[string]string
+}
+
+// attr 读取输入框属性，不存在时返回空字符串
+func (f *formField) attr(name string) string {
+	return f.attrs[name]
+}
+
+// LoginForm 从认证页面中解析出的登录表单
+type LoginForm struct {
+	Action string
+	Method string
+	// Values 表单中的默认值，包括隐藏字段与第一个提交按钮
+	Values        url.Values
+	UsernameField string
+	PasswordField string
+	fields        []formField
+	// source 表单所在页面的源码，部分认证系统的加密参数写在页面脚本中
+	source []byte
+}
+
+// parseLoginForm 在页面中找到包含密码框的表单，并推断用户名与密码字段
+func parseLoginForm(pageURL string, body []byte) (*LoginForm, error) {
+	doc, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("解析认证页面失败: %w", err)
+	}
+
+	var forms []*html.Node
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "form" {
+			forms = append(forms, node)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	for _, node := range forms {
+		form := newLoginForm(node)
+		if form.PasswordField == "" {
+			continue
+		}
+
+		base, err := url.Parse(pageURL)
+		if err != nil {
+			return nil, fmt.Errorf("解析登录链接失败: %w", err)
+		}
+		action, err := url.Parse(strings.TrimSpace(htmlAttr(node, "action")))
+		if err != nil {
+			return nil, fmt.Errorf("表单提交地址无效: %w", err)
+		}
+		form.Action = base.ResolveReference(action).String()
+		form.source = body
+		return form, nil
+	}
+
+	return nil, fmt.Errorf("认证页面中没有包含密码框的登录表单")
+}
+
+// newLoginForm 读取表单中的字段与默认值
+func newLoginForm(node *html.Node) *LoginForm {
+	form := &LoginForm{
+		Method: strings.ToUpper(htmlAttr(node, "method")),
+		Values: url.Values{},
+	}
+	if form.Method != http.MethodGet {
+		form.Method = http.MethodPost
+	}
+
+	submitted := false
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			field := formField{Tag: node.Data, attrs: map[string]string{}}
+			for _, attr := range node.Attr {
+				field.attrs[attr.Key] = attr.Val
+			}
+			field.Name = field.attr("name")
+			field.Type = strings.ToLower(field.attr("type"))
+
+			switch node.Data {
+			case "input":
+				if field.Type == "" {
+					field.Type = "text"
+				}
+				form.fields = append(form.fields, field)
+				switch field.Type {
+				case "submit", "image":
+					// 浏览器只提交被点击的按钮，这里视为点击第一个
+					if field.Name != "" && !submitted {
+						form.Values.Set(field.Name, field.attr("value"))
+						submitted = true
+					}
+				case "button", "reset", "file":
+				case "checkbox", "radio":
+					if _, checked := field.attrs["checked"]; checked && field.Name != "" {
+						value := field.attr("value")
+						if value == "" {
+							value = "on"
+						}
+						form.Values.Add(field.Name, value)
+					}
+				default:
+					if field.Name != "" {
+						form.Values.Set(field.Name, field.attr("value"))
+					}
+				}
+			case "select":
+				if field.Name != "" {
+					form.Values.Set(field.Name, selectedOption(node))
+				}
+				return
+			case "textarea":
+				if field.Name != "" {
+					form.Values.Set(field.Name, htmlText(node))
+				}
+				return
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	form.PasswordField = form.findField(passwordFieldSelector, isPasswordField)
+	form.UsernameField = form.findField(usernameFieldSelector, isUsernameField)
+	return form
+}
+
+// selectedOption 返回下拉框中选中的值，没有选中时取第一项
+func selectedOption(node *html.Node) string {
+	first, found := "", false
+	var selected *string
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "option" && selected == nil {
+			value := htmlAttr(node, "value")
+			if !hasAttr(node, "value") {
+				value = htmlText(node)
+			}
+			if !found {
+				first, found = value, true
+			}
+			if hasAttr(node, "selected") {
+				selected = &value
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	if selected != nil {
+		return *selected
+	}
+	return first
+}
+
+// hasAttr 判断元素是否带有属性
+func hasAttr(node *html.Node, name string) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isUsernameField 可以填写账号的输入框
+func isUsernameField(field *formField) bool {
+	switch field.Type {
+	case "text", "email", "tel", "number":
+		return field.Tag == "input" && field.Name != ""
+	}
+	return false
+}
+
+// isPasswordField 密码输入框
+func isPasswordField(field *formField) bool {
+	return field.Tag == "input" && field.Type == "password" && field.Name != ""
+}
+
+// findField 按与 FindElementRobust 相同的顺序查找字段：主要选择器、备选选择器，最后按属性模糊匹配
+func (f *LoginForm) findField(selector ElementSelector, candidate func(*formField) bool) string {
+	selectors := append([]string{selector.Primary}, selector.Alternatives...)
+	for _, css := range selectors {
+		for i := range f.fields {
+			field := &f.fields[i]
+			if candidate(field) && matchSimpleSelector(css, field) {
+				return field.Name
+			}
+		}
+	}
+
+	for i := range f.fields {
+		field := &f.fields[i]
+		if !candidate(field) {
+			continue
+		}
+		for _, attr := range selector.Attributes {
+			value := strings.ToLower(field.attr(attr))
+			for _, text := range selector.TextContains {
+				if value != "" && strings.Contains(value, strings.ToLower(text)) {
+					return field.Name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// simpleSelectorPattern 解析 tag[attr='value']、tag[attr*='value'] 形式的选择器
+var simpleSelectorPattern = regexp.MustCompile(`^(\w*)\[(\w+)(\*?)=['"]?([^'"\]]*)['"]?\]$`)
+
+// matchSimpleSelector 判断字段是否匹配选择器，只支持登录页面查找输入框用到的几种写法
+func matchSimpleSelector(selector string, field *formField) bool {
+	switch {
+	case strings.HasPrefix(selector, "#"):
+		return field.attr("id") == selector[1:]
+	case strings.HasPrefix(selector, "."):
+		return containsWord(field.attr("class"), selector[1:])
+	}
+
+	match := simpleSelectorPattern.FindStringSubmatch(selector)
+	if match == nil {
+		return false
+	}
+	tag, attr, contains, value := match[1], match[2], match[3] == "*", match[4]
+	if tag != "" && tag != field.Tag {
+		return false
+	}
+	actual := field.attr(attr)
+	if attr == "type" {
+		actual = field.Type
+	}
+	if contains {
+		return strings.Contains(actual, value)
+	}
+	return actual == value
+}
+
+// containsWord 判断以空格分隔的列表中是否包含 word
+func containsWord(list, word string) bool {
+	for _, item := range strings.Fields(list) {
+		if item == word {
+			return true
+		}
+	}
+	return false
+}
+
+// FormClient 通过 HTTP 直接提交认证页面表单的客户端，适用于只有一个登录表单的简单认证页面
+type FormClient struct {
+	pageURL    string
+	httpClient *http.Client
+}
+
+// NewFormClient 创建表单客户端，保留会话 Cookie
+func NewFormClient(pageURL string, timeout time.Duration) (*FormClient, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析登录链接失败: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("登录链接不完整: %s", pageURL)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	return &FormClient{
+		pageURL: pageURL,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Jar:       jar,
+			Transport: portalTransport,
+		},
+	}, nil
+}
+
+// FetchForm 请求认证页面并解析登录表单
+func (c *FormClient) FetchForm(ctx context.Context) (*LoginForm, error) {
+	finalURL, body, err := fetchPortalPage(ctx, c.httpClient, c.pageURL)
+	if err != nil {
+		return nil, err
+	}
+	return parseLoginForm(finalURL, body)
+}
+
+// Submit 提交表单，返回跟随重定向后的地址与页面内容
+func (c *FormClient) Submit(ctx context.Context, form *LoginForm) (string, []byte, error) {
+	var req *http.Request
+	var err error
+	if form.Method == http.MethodGet {
+		target, parseErr := url.Parse(form.Action)
+		if parseErr != nil {
+			return "", nil, fmt.Errorf("表单提交地址无效: %w", parseErr)
+		}
+		target.RawQuery = form.Values.Encode()
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, form.Action, strings.NewReader(form.Values.Encode()))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Referer", c.pageURL)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("提交登录表单失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256*1024))
+	if err != nil {
+		return "", nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.Request.URL.String(), body, fmt.Errorf("提交登录表单返回状态码 %d", resp.StatusCode)
+	}
+	return resp.Request.URL.String(), body, nil
+}
+
+// formVariant 表单驱动针对具体认证系统的差异
+type formVariant interface {
+	// prepare 填入账号密码并补全认证系统需要的字段
+	prepare(form *LoginForm, config *Config) error
+	// verify 根据提交后的页面判断是否认证成功
+	verify(ctx context.Context, result *LoginResult, finalURL string, body []byte) error
+	// logout 注销在线用户，不支持时返回 ErrLogoutNotSupported
+	logout(ctx context.Context, client *FormClient, config *Config, result *LoginResult) error
+}
+
+// formDriver 通过 HTTP 提交认证页面登录表单的驱动
+type formDriver struct {
+	name    string
+	variant formVariant
+}
+
+func init() {
+	RegisterLoginDriver("form", func() LoginDriver { return &formDriver{name: "form", variant: genericForm{}} })
+}
+
+func (d *formDriver) Login(ctx context.Context, config *Config) (*LoginResult, error) {
+	log.Printf("通过提交登录表单认证 (%s)...", d.name)
+	start := time.Now()
+	result := newLoginResult(d.name, config)
+
+	client, err := NewFormClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	var form *LoginForm
+	err = result.runStep(ctx, "读取登录表单", func() (err error) {
+		form, err = client.FetchForm(ctx)
+		if err != nil {
+			return err
+		}
+		return d.variant.prepare(form, config)
+	})
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+	log.Printf("登录表单: %s %s，用户名字段 %s，密码字段 %s", form.Method, form.Action, form.UsernameField, form.PasswordField)
+
+	err = result.runStep(ctx, "提交登录表单", func() error {
+		finalURL, body, err := client.Submit(ctx, form)
+		if err != nil {
+			return err
+		}
+		result.FinalURL = finalURL
+		return d.variant.verify(ctx, result, finalURL, body)
+	})
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	log.Printf("表单认证成功: %s", result.Message)
+	return result, result.finish(start, nil)
+}
+
+func (d *formDriver) Probe(ctx context.Context, config *Config) (string, error) {
+	client, err := NewFormClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	form, err := client.FetchForm(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := d.variant.prepare(form, config); err != nil {
+		return "", err
+	}
+
+	result := fmt.Sprintf("连接测试结果:\n- 登录表单: %s %s\n- 用户名字段: %s\n- 密码字段: %s",
+		form.Method, form.Action, form.UsernameField, form.PasswordField)
+	log.Println(result)
+	return result, nil
+}
+
+func (d *formDriver) Logout(ctx context.Context, config *Config) (*LoginResult, error) {
+	start := time.Now()
+	result := newLoginResult(d.name, config)
+
+	client, err := NewFormClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+	return result, result.finish(start, d.variant.logout(ctx, client, config, result))
+}
+
+// genericForm 通用的表单认证：按输入框特征填写账号密码，根据提交后的页面判断结果
+type genericForm struct{}
+
+func (genericForm) prepare(form *LoginForm, config *Config) error {
+	if form.UsernameField == "" {
+		return fmt.Errorf("登录表单中找不到用户名输入框")
+	}
+	form.Values.Set(form.UsernameField, config.Countindex)
+	form.Values.Set(form.PasswordField, config.Passwordindex)
+	return nil
+}
+
+// formAlertPattern 页面脚本中 alert 弹出的提示
+var formAlertPattern = regexp.MustCompile(`alert\(\s*['"]([^'"]+)['"]\s*\)`)
+
+// formValidationKeywords 页面自带的输入校验脚本中的提示，不是服务器返回的认证结果
+var formValidationKeywords = []string{"请输入", "不能为空", "请填写", "please enter", "please input", "is required"}
+
+// formAlert 返回页面中最能说明认证结果的 alert 提示：先取能归类为认证错误的提示，
+// 再取第一个不是输入校验的提示，避免把页面自带的校验脚本当作认证结果
+func formAlert(body []byte) string {
+	var alerts []string
+	for _, match := range formAlertPattern.FindAllSubmatch(body, -1) {
+		alerts = append(alerts, strings.TrimSpace(string(match[1])))
+	}
+
+	for _, alert := range alerts {
+		if classifyPortalMessage(alert) != nil {
+			return alert
+		}
+	}
+	for _, alert := range alerts {
+		lower := strings.ToLower(alert)
+		validation := false
+		for _, keyword := range formValidationKeywords {
+			if strings.Contains(lower, keyword) {
+				validation = true
+				break
+			}
+		}
+		if !validation {
+			return alert
+		}
+	}
+	return ""
+}
+
+// formSuccessKeywords 认证成功页面常见的文字
+var formSuccessKeywords = []string{"登录成功", "认证成功", "成功登录", "已登录", "login success", "logged in"}
+
+func (genericForm) verify(ctx context.Context, result *LoginResult, finalURL string, body []byte) error {
+	alert := formAlert(body)
+	result.Message = alert
+	if result.Message == "" {
+		if doc, err := html.Parse(bytes.NewReader(body)); err == nil {
+			result.Message = pageTitle(doc)
+		}
+	}
+
+	if isSuccessPage(finalURL) {
+		result.Outcome = LoginOutcomeSuccess
+		return nil
+	}
+	if form, err := parseLoginForm(finalURL, body); err == nil && form.PasswordField != "" {
+		// 仍然返回登录表单，说明认证被拒绝
+		if alert == "" {
+			alert = "提交后仍停留在登录页面"
+		}
+		return portalFailure(alert)
+	}
+	if err := classifyPortalMessage(alert); err != nil {
+		return err
+	}
+
+	lower := strings.ToLower(string(body))
+	for _, keyword := range formSuccessKeywords {
+		if strings.Contains(lower, keyword) {
+			result.Outcome = LoginOutcomeSuccess
+			return nil
+		}
+	}
+	if connected, _, err := CheckConnectivity(ctx, 5*time.Second); err == nil && connected {
+		result.Outcome = LoginOutcomeSuccess
+		log.Println("连通性检测通过，认证成功")
+		return nil
+	}
+
+	result.Outcome = LoginOutcomeUnverified
+	return ErrLoginUnverified
+}
+
+func (genericForm) logout(context.Context, *FormClient, *Config, *LoginResult) error {
+	return ErrLogoutNotSupported
+}
+
+// pageTitle 读取页面标题
+func pageTitle(doc *html.Node) string {
+	if doc.Type == html.ElementNode && doc.Data == "title" {
+		return htmlText(doc)
+	}
+	for child := doc.FirstChild; child != nil; child = child.NextSibling {
+		if title := pageTitle(child); title != "" {
+			return title
+		}
+	}
+	return ""
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseLoginForm(t *testing.T) {
+	page := `<html><body>
+<form action="/search"><input name="q" placeholder="搜索"></form>
+<form id="login" method="post" action="auth/login.do">
+  <input type="hidden" name="token" value="abc123">
+  <input name="acc" placeholder="请输入学号">
+  <input type="password" name="pw">
+  <select name="domain"><option value="">请选择</option><option value="cmcc" selected>移动</option></select>
+  <input type="checkbox" name="remember" checked>
+  <input type="checkbox" name="agree">
+  <input type="submit" name="btn" value="登录"><input type="submit" name="other" value="重置">
+</form></body></html>`
+
+	form, err := parseLoginForm("http://10.0.0.5/portal/index.html", []byte(page))
+	if err != nil {
+		t.Fatalf("解析登录表单失败: %v", err)
+	}
+	if form.Action != "http://10.0.0.5/portal/auth/login.do" || form.Method != http.MethodPost {
+		t.Errorf("提交地址为 %s %s", form.Method, form.Action)
+	}
+	if form.UsernameField != "acc" || form.PasswordField != "pw" {
+		t.Errorf("用户名字段 %q，密码字段 %q", form.UsernameField, form.PasswordField)
+	}
+
+	want := "acc=&btn=%E7%99%BB%E5%BD%95&domain=cmcc&pw=&remember=on&token=abc123"
+	if got := form.Values.Encode(); got != want {
+		t.Errorf("表单默认值为 %s，期望 %s", got, want)
+	}
+
+	if _, err := parseLoginForm("http://10.0.0.5/", []byte(`<form><input name="q"></form>`)); err == nil {
+		t.Error("没有密码框的页面应返回错误")
+	}
+}
+
+// mockDrcom 模拟 Dr.COM 网页认证，登录页面脚本要求以 MD5 提交密码
+type mockDrcom struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	online bool
+	form   map[string]string
+}
+
+const (
+	mockDrcomPID  = "2"
+	mockDrcomCalg = "12345678"
+)
+
+func newMockDrcom(t *testing.T) *mockDrcom {
+	t.Helper()
+
+	drcom := &mockDrcom{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			drcom.handleLogin(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><head><title>上网登录页</title><script src="a41.js"></script>
+<script>ps=1;pid='%s';calg='%s';</script></head><body>
+<form name="f1" method="post" action="" onsubmit="return ee(1)">
+<input name="DDDDD" type="text"><input name="upass" type="password">
+<input type="hidden" name="R1" value="0"><input type="hidden" name="R6" value="0">
+<input type="submit" name="0MKKey" value="登录"></form></body></html>`, mockDrcomPID, mockDrcomCalg)
+	})
+	mux.HandleFunc("/F.htm", func(w http.ResponseWriter, r *http.Request) {
+		drcom.mu.Lock()
+		drcom.online = false
+		drcom.mu.Unlock()
+		fmt.Fprint(w, `<html><head><title>信息页</title><script>Msg=14;time='';flow='';</script></head></html>`)
+	})
+
+	drcom.Server = httptest.NewServer(mux)
+	t.Cleanup(drcom.Close)
+	return drcom
+}
+
+func (d *mockDrcom) handleLogin(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.form = map[string]string{}
+	for key := range r.PostForm {
+		d.form[key] = r.PostForm.Get(key)
+	}
+
+	sum := md5.Sum([]byte(mockDrcomPID + "correct-password" + mockDrcomCalg))
+	expected := hex.EncodeToString(sum[:]) + mockDrcomCalg + mockDrcomPID
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	switch {
+	case d.form["upass"] != expected:
+		fmt.Fprint(w, `<html><head><title>信息返回窗</title><script>Msg=01;time='';flow='';msga='ldap auth error';</script></head></html>`)
+	case d.online:
+		fmt.Fprint(w, `<html><head><title>信息返回窗</title><script>Msg=02;time='';flow='';msga='';</script></head></html>`)
+	default:
+		d.online = true
+		fmt.Fprint(w, `<html><head><title>认证成功页</title></head><body>您已经成功登录。</body></html>`)
+	}
+}
+
+func TestDrcomDriver(t *testing.T) {
+	drcom := newMockDrcom(t)
+	config := DefaultConfig()
+	config.Webindex = drcom.URL + "/"
+	config.Countindex = "201900001"
+	config.Passwordindex = "wrong-password"
+
+	// auto 驱动识别出 Dr.COM 后使用 drcom 驱动
+	driver, err := NewLoginDriver("auto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := driver.Login(context.Background(), config)
+	if !errors.Is(err, ErrBadCredentials) {
+		t.Fatalf("密码错误时期望 %v，实际为 %v", ErrBadCredentials, err)
+	}
+	if result.Driver != "drcom" || result.Message != "密码错误" {
+		t.Errorf("登录结果不正确: %+v", result)
+	}
+
+	config.Passwordindex = "correct-password"
+	result, err = driver.Login(context.Background(), config)
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if result.Outcome != LoginOutcomeSuccess {
+		t.Errorf("登录结果不正确: %+v", result)
+	}
+	for key, value := range map[string]string{"DDDDD": "201900001", "0MKKey": "登录", "R1": "0", "R2": "1", "R6": "0", "para": "00"} {
+		if drcom.form[key] != value {
+			t.Errorf("提交的 %s 为 %q，期望 %q", key, drcom.form[key], value)
+		}
+	}
+
+	// 账号正在使用中说明被其他设备占用，本机并未在线
+	if _, err := driver.Login(context.Background(), config); !errors.Is(err, ErrDeviceLimit) {
+		t.Errorf("账号使用中期望 %v，实际为 %v", ErrDeviceLimit, err)
+	}
+
+	result, err = driver.Logout(context.Background(), config)
+	if err != nil || result.Outcome != LoginOutcomeSuccess {
+		t.Errorf("注销失败: %v %+v", err, result)
+	}
+}
+
+func TestDrcomMessage(t *testing.T) {
+	tests := []struct {
+		body     string
+		wantCode string
+		wantKind error
+	}{
+		{`<script>Msg=01;time='';flow='';msga='';</script>`, "01", ErrBadCredentials},
+		{`<script>Msg=02;time='';flow='';msga='';</script>`, "02", ErrDeviceLimit},
+		{`<script>Msg=04;time='';flow='';msga='';</script>`, "04", ErrAccountSuspended},
+		{`<script>Msg=05;time='';flow='';msga='';</script>`, "05", ErrAccountSuspended},
+		{`<script>Msg=3;time='';flow='';msga='';</script>`, "03", nil},
+		{`<script>Msg=15;time='';flow='';</script>`, "15", nil},
+		{`<html>认证成功页</html>`, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantCode, func(t *testing.T) {
+			code, result := drcomMessage([]byte(tt.body))
+			if code != tt.wantCode || result.Kind != tt.wantKind {
+				t.Errorf("结果为 %q %v，期望 %q %v", code, result.Kind, tt.wantCode, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestFormAlert(t *testing.T) {
+	// 页面自带的校验脚本排在服务器返回的结果之前
+	validator := `<script>function check(){if(!f.account.value){alert('请输入用户名');return false}}</script>`
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"已知错误优先", validator + `<script>alert('提示信息');alert('用户名或密码错误');</script>`, "用户名或密码错误"},
+		{"跳过输入校验", validator + `<script>alert('系统繁忙，请稍后再试');</script>`, "系统繁忙，请稍后再试"},
+		{"只有输入校验", validator, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formAlert([]byte(tt.body)); got != tt.want {
+				t.Errorf("提示为 %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormDriver(t *testing.T) {
+	var submitted map[string][]string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><form method="post" action="/auth">
+<input id="user" name="account" placeholder="账号"><input name="secret" type="password" placeholder="密码">
+<input type="hidden" name="nasip" value="10.0.0.2"></form></body></html>`)
+	})
+	mux.HandleFunc("/auth", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		submitted = r.PostForm
+		if r.PostForm.Get("secret") != "correct-password" {
+			fmt.Fprint(w, `<html><script>alert('用户名或密码错误');history.back();</script></html>`)
+			return
+		}
+		http.Redirect(w, r, "/success.html", http.StatusFound)
+	})
+	mux.HandleFunc("/success.html", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>认证成功</title></head></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.Webindex = server.URL + "/login.html"
+	config.Countindex = "201900001"
+	config.Passwordindex = "wrong-password"
+
+	driver, err := NewLoginDriver("form")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := driver.Login(context.Background(), config); !errors.Is(err, ErrBadCredentials) {
+		t.Errorf("密码错误时期望 %v，实际为 %v", ErrBadCredentials, err)
+	}
+
+	config.Passwordindex = "correct-password"
+	result, err := driver.Login(context.Background(), config)
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if result.Outcome != LoginOutcomeSuccess || !strings.HasSuffix(result.FinalURL, "/success.html") || len(result.Steps) != 2 {
+		t.Errorf("登录结果不正确: %+v", result)
+	}
+	if submitted["account"][0] != "201900001" || submitted["nasip"][0] != "10.0.0.2" {
+		t.Errorf("提交的表单不正确: %v", submitted)
+	}
+
+	if _, err := driver.Logout(context.Background(), config); !errors.Is(err, ErrLogoutNotSupported) {
+		t.Errorf("通用表单驱动注销期望 %v，实际为 %v", ErrLogoutNotSupported, err)
+	}
+}
@@ -1,7 +1,8 @@
 import './style.css';
 
-import {SaveValue, ReadData, Loginyzu, EnableAutoStart, DisableAutoStart, TestConnection, DetectNetworkLoginPage, AutoDetectAndSaveLoginURL, GetNetworkStatus} from '../wailsjs/go/main/App';
-import { Quit } from '../wailsjs/runtime/runtime';
+import {GetConfig, UpdateConfig, StartLogin, CancelOperation, GetLoginSteps, Logout, GetSessionInfo, SwitchProfile, ListOperators, EnableAutoStart, DisableAutoStart, IsAutoStartEnabled, TestConnection, DetectNetworkLoginPage, AutoDetectAndSaveLoginURL, GetNetworkStatus} from '../wailsjs/go/main/App';
+import {main} from '../wailsjs/go/models';
+import { Quit, EventsOn } from '../wailsjs/runtime/runtime';
 import 'sober';
 
 let webindex = document.getElementById("webindex");
@@ -11,8 +12,18 @@ let operatorindex = document.getElementById("operatorindex");
 let autostartindex = document.getElementById("autostartindex");
 let operatorindex_items = document.querySelectorAll('s-segmented-button-item');
 let testconnectindex = document.getElementById("testconnectindex");
+let logoutindex = document.getElementById("logoutindex");
+let sessionindex = document.getElementById("session");
+let stepsindex = document.getElementById("steps");
+let profileindex = document.getElementById("profileindex");
+
+// 账号选择框中"新建账号"选项的值
+const newProfileOption = "__new__";
 let detectLoginPageBtn = document.getElementById("detectLoginPage");
 
+// 当前配置，保存时保留界面上没有的配置项
+let currentConfig = new main.Config();
+
 // 设置一个定时器变量
 let typingTimer;
 let doneTypingInterval = 500; // 时间间隔（毫秒）
@@ -20,18 +31,97 @@ let doneTypingInterval = 500; // 时间间隔（毫秒）
 // 用户停止输入后的处理函数
 function doneTyping() {
 
+    currentConfig = main.Config.createFrom({
+        ...currentConfig,
+        [webindex.id]: webindex.value,
+        [countindex.id]: countindex.value,
+        [passwordindex.id]: passwordindex.value,
+        [operatorindex.id]: operatorindex.value,
+        [autostartindex.id]: autostartindex.checked
+    });
+
+    return UpdateConfig(currentConfig).catch((err) => {
+        console.error(err);
+        showSnackbar("保存失败: " + err.toString());
+    });
+}
+
+// 按认证页面提供的服务重建运营商按钮，保留当前选中的运营商。
+// 按钮的值为保存到配置中的名称，如页面上的"中国移动(100M)"对应配置中的"移动"
+function renderOperators(operators) {
+    if (!operators || operators.length === 0) {
+        return;
+    }
+    const selected = operatorindex.value;
+    operatorindex.innerHTML = "";
+    operators.forEach((operator) => {
+        const item = document.createElement('s-segmented-button-item');
+        item.value = operator.name || operator.label;
+        item.textContent = operator.label;
+        operatorindex.appendChild(item);
+    });
+    operatorindex.value = selected;
+    operatorindex_items = operatorindex.querySelectorAll('s-segmented-button-item');
+}
+
+// 将配置填入界面，切换账号后同样调用
+function fillConfig(config) {
+    currentConfig = config;
+    webindex.value = config.webindex || "";
+    countindex.value = config.countindex || "";
+    passwordindex.value = config.passwordindex || "";
+    operatorindex.value = config.operatorindex || "";
+    autostartindex.checked = config.autostartindex;
+
+    profileindex.innerHTML = "";
+    const names = (config.profiles || []).map((profile) => profile.name);
+    if (!names.includes(config.default_profile)) {
+        names.push(config.default_profile);
+    }
+    names.forEach((name) => {
+        const option = document.createElement('option');
+        option.value = name;
+        option.textContent = name;
+        profileindex.appendChild(option);
+    });
+    const option = document.createElement('option');
+    option.value = newProfileOption;
+    option.textContent = "＋ 新建账号…";
+    profileindex.appendChild(option);
+    profileindex.value = config.default_profile;
+}
+
+// 切换账号前先保存正在编辑的内容，新建账号沿用当前的认证地址
+profileindex.addEventListener('change', async () => {
+    let name = profileindex.value;
+    clearTimeout(typingTimer);
+    await doneTyping();
+
+    if (name === newProfileOption) {
+        name = (window.prompt("新账号名称，例如 电信 或 张三") || "").trim();
+        if (!name) {
+            profileindex.value = currentConfig.default_profile;
+            return;
+        }
+        if (!(currentConfig.profiles || []).some((profile) => profile.name === name)) {
+            currentConfig = main.Config.createFrom({
+                ...currentConfig,
+                profiles: [...(currentConfig.profiles || []), {name: name, webindex: webindex.value}],
+            });
+            await doneTyping();
+        }
+    }
+
     try {
-        SaveValue({
-            [webindex.id]: webindex.value,
-            [countindex.id]: countindex.value,
-            [passwordindex.id]: passwordindex.value,
-            [operatorindex.id]: operatorindex.value,
-            [autostartindex.id]: autostartindex.checked.toString()
-        })
+        fillConfig(await SwitchProfile(name));
+        showSnackbar("已切换到账号 " + name);
+        refreshSession();
     } catch (err) {
-        console.error(err); 
+        console.error(err);
+        showSnackbar("切换账号失败: " + err.toString());
+        profileindex.value = currentConfig.default_profile;
     }
-}
+});
 
 // 在用户输入时清除定时器
 webindex.addEventListener('input', () => {
@@ -83,21 +173,128 @@ testconnectindex.addEventListener('click', async () => {
     }
 });
 
+// 进行中的登录操作 ID，再次右键时取消
+let currentLogin = null;
+
+// 在后台开始登录，已有登录进行中时取消它
+async function startOrCancelLogin() {
+    if (currentLogin) {
+        try {
+            await CancelOperation(currentLogin);
+            showSnackbar("正在取消登录...");
+            return;
+        } catch (err) {
+            // 登录已经结束，重新开始
+            currentLogin = null;
+        }
+    }
+    await renderLoginSteps();
+    try {
+        currentLogin = await StartLogin();
+        showSnackbar("正在登录，再次右键可取消...");
+    } catch (err) {
+        console.error(err);
+        showSnackbar(describeLoginError(err));
+    }
+}
+
+// 登录步骤的状态文字
+const stepStatusText = {
+    started: "进行中",
+    retrying: "重试中",
+    succeeded: "完成",
+    failed: "失败",
+};
+
+// 按登录脚本列出待执行的步骤，收到第一个步骤事件时再显示
+async function renderLoginSteps() {
+    stepsindex.textContent = "";
+    stepsindex.classList.remove('visible');
+    try {
+        const steps = await GetLoginSteps();
+        steps.forEach((step) => stepItem(step.name));
+    } catch (err) {
+        console.error('Failed to load login steps:', err);
+    }
+}
+
+// 返回步骤对应的列表项，脚本之外的步骤追加到末尾
+function stepItem(name) {
+    for (const item of stepsindex.children) {
+        if (item.dataset.step === name) {
+            return item;
+        }
+    }
+    const item = document.createElement('li');
+    item.dataset.step = name;
+    item.textContent = name;
+    stepsindex.appendChild(item);
+    return item;
+}
+
+// 登录步骤状态变化时更新进度列表
+EventsOn('operation:step', (event) => {
+    // StartLogin 返回操作 ID 之前到达的事件同样属于本次登录
+    if (!event.operation.startsWith('login-') || (currentLogin && event.operation !== currentLogin)) {
+        return;
+    }
+    const item = stepItem(event.step);
+    item.className = event.status;
+    let text = `${event.step} · ${stepStatusText[event.status] || event.status}`;
+    if (event.attempt > 1) {
+        text += ` · 第 ${event.attempt} 次`;
+    }
+    text += ` · ${(event.elapsed_ms / 1000).toFixed(1)}s`;
+    item.textContent = text;
+    item.title = event.error || "";
+    stepsindex.classList.add('visible');
+});
+
+// 后台登录结束时显示结果
+EventsOn('operation:finished', (event) => {
+    if (event.kind !== 'login') {
+        return;
+    }
+    currentLogin = null;
+    // 登录失败时保留进度列表，便于看出失败的步骤
+    if (!event.error || event.canceled) {
+        stepsindex.classList.remove('visible');
+    }
+    console.log("登录结果:", event);
+    if (event.canceled) {
+        showSnackbar("已取消登录");
+    } else if (event.error) {
+        showSnackbar(describeLoginError(event.error));
+    } else {
+        showSnackbar(describeLoginResult(event.result));
+        refreshSession();
+    }
+});
+
 // 添加实际登录按钮功能
-testconnectindex.addEventListener('contextmenu', async (e) => {
+testconnectindex.addEventListener('contextmenu', (e) => {
     e.preventDefault(); // 阻止右键菜单
+    startOrCancelLogin();
+});
+
+// 下线按钮：注销当前在线用户，网络守护暂停到下次登录
+logoutindex.addEventListener('click', async () => {
     try {
-        await Loginyzu();
-        showSnackbar("正在登录...");
+        showSnackbar("正在下线...");
+        const result = await Logout();
+        console.log("注销结果:", result);
+        showSnackbar(result.outcome === "success" ? "已下线" : "已提交注销，但未能确认是否下线");
+        refreshSession();
     } catch (err) {
         console.error(err);
-        showSnackbar("登录失败: " + err.toString());
+        showSnackbar("下线失败: " + (err && err.message ? err.message : String(err)));
     }
 });
 
 // 添加工具提示
 detectLoginPageBtn.title = "自动检测校园网登录页面，无需手动输入URL";
-testconnectindex.title = "左键：测试网络连接 | 右键：执行自动登录";
+testconnectindex.title = "左键：测试网络连接 | 右键：执行自动登录，登录中再次右键取消";
+logoutindex.title = "注销当前在线的校园网账号";
 
 // 自动检测登录页面功能
 detectLoginPageBtn.addEventListener('click', async () => {
@@ -135,6 +332,69 @@ detectLoginPageBtn.addEventListener('click', async () => {
     }
 });
 
+// 将登录结果转换为提示文字
+function describeLoginResult(result) {
+    const seconds = (result.duration_ms / 1000).toFixed(1);
+    switch (result.outcome) {
+        case "success":
+            if (result.fallback > 0) {
+                return `已切换到 ${result.profile} 登录成功 (${seconds}s)`;
+            }
+            return `登录成功 (${seconds}s${result.ip ? ", IP " + result.ip : ""})`;
+        case "unverified":
+            return "已提交登录，但未能确认结果";
+        default:
+            return "登录失败: " + (result.message || result.error || "未知错误");
+    }
+}
+
+// 刷新在线信息，查询失败或未在线时隐藏
+async function refreshSession() {
+    try {
+        const info = await GetSessionInfo();
+        if (!info.online) {
+            sessionindex.classList.remove('visible');
+            return;
+        }
+
+        const lines = [
+            [info.user_name || info.user_id, info.ip].filter(Boolean).join(" · "),
+            [
+                info.online_time && "在线 " + info.online_time,
+                info.used_traffic && "已用 " + info.used_traffic,
+                info.balance && "余额 " + info.balance,
+            ].filter(Boolean).join(" · "),
+        ].filter(Boolean);
+        sessionindex.textContent = "";
+        lines.forEach((line) => {
+            const div = document.createElement('div');
+            div.textContent = line;
+            sessionindex.appendChild(div);
+        });
+        sessionindex.classList.add('visible');
+    } catch (err) {
+        console.error('Failed to query session info:', err);
+        sessionindex.classList.remove('visible');
+    }
+}
+
+// 认证页面拒绝登录时的提示，对应后端的错误代码
+const loginErrorHints = {
+    bad_credentials: "账号或密码错误，请检查设置",
+    account_suspended: "账号已欠费或停用，请先充值",
+    device_limit: "在线设备数已达上限，请先下线其他设备",
+    operator_unavailable: "所选运营商不可用，请检查运营商设置",
+    already_online: "账号已在线，无需重复登录",
+};
+
+// 将登录错误转换为提示文字，认证页面拒绝的错误带有 code
+function describeLoginError(err) {
+    if (err && err.code && loginErrorHints[err.code]) {
+        return loginErrorHints[err.code];
+    }
+    return "登录失败: " + (err && err.message ? err.message : String(err));
+}
+
 // 显示网络状态信息
 function showNetworkStatus(status) {
     const statusDiv = document.createElement('div');
@@ -166,6 +426,10 @@ function showNetworkStatus(status) {
             statusHTML += `<p style="color: red;">❌ 检测错误: ${status.detection_error}</p>`;
         }
     }
+
+    if (status.vendor_name) {
+        statusHTML += `<p>🏷️ 认证系统: ${status.vendor_name} ${status.vendor_version || ""}</p>`;
+    }
     
     // 显示原始状态数据（调试用）
     statusHTML += `<hr><details><summary>详细数据</summary><pre style="font-size: 12px; overflow: auto;">${JSON.stringify(status, null, 2)}</pre></details>`;
@@ -250,24 +514,29 @@ function showDetailedResult(result) {
     }, 5000);
 }
 
-ReadData()
-    .then((data) => {
-        webindex.value = data.webindex;
-        countindex.value = data.countindex;
-        passwordindex.value = data.passwordindex;
-        operatorindex.value = data.operatorindex;
-        autostartindex.checked = data.autostartindex == "true";
+GetConfig()
+    .then((config) => {
+        fillConfig(config);
+
+        // 开关以系统中实际登记的自启动项为准
+        IsAutoStartEnabled()
+            .then((enabled) => {
+                autostartindex.checked = enabled;
+            })
+            .catch((err) => {
+                console.error('Failed to query auto start:', err);
+            });
         
-        if (data.autostartindex == "true") {
-            (async () => {
-                try {
-                    await Loginyzu();
-                    showSnackbar("测试连接已触发");
-                } catch (err) {
-                    console.error(err);
-                    showSnackbar(err.toString());
-                }
-            })();
+        refreshSession();
+
+        ListOperators()
+            .then(renderOperators)
+            .catch((err) => {
+                console.error('Failed to list operators:', err);
+            });
+
+        if (config.autostartindex) {
+            startOrCancelLogin();
         }
 
         setTimeout(() => {
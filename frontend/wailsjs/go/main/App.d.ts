@@ -1,22 +1,51 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {main} from '../models';
 
 export function AutoDetectAndSaveLoginURL():Promise<string>;
 
+export function CancelOperation(arg1:string):Promise<void>;
+
 export function DetectNetworkLoginPage():Promise<string>;
 
 export function DisableAutoStart():Promise<void>;
 
 export function EnableAutoStart():Promise<void>;
 
+export function GetBrowser():Promise<main.BrowserInfo>;
+
+export function GetConfig():Promise<main.Config>;
+
+export function GetLoginSteps():Promise<Array<main.RecipeStep>>;
+
 export function GetNetworkStatus():Promise<Record<string, any>>;
 
+export function GetSessionInfo():Promise<main.SessionInfo>;
+
+export function IsAutoStartEnabled():Promise<boolean>;
+
+export function ListOperations():Promise<Array<main.OperationInfo>>;
+
+export function ListOperators():Promise<main.Operator[]>;
+
+export function ListProfiles():Promise<main.Profile[]>;
+
+export function ListRecipes():Promise<main.Recipe[]>;
+
 export function LoginWithAdvancedOptions(arg1:boolean,arg2:number):Promise<void>;
 
-export function Loginyzu():Promise<void>;
+export function LoginWithProfile(arg1:string):Promise<main.LoginResult>;
+
+export function Loginyzu():Promise<main.LoginResult>;
 
-export function ReadData():Promise<Record<string, string>>;
+export function Logout():Promise<main.LoginResult>;
 
-export function SaveValue(arg1:Record<string, string>):Promise<void>;
+export function RemoveProfile(arg1:string):Promise<void>;
+
+export function StartLogin():Promise<string>;
+
+export function SwitchProfile(arg1:string):Promise<main.Config>;
 
 export function TestConnection():Promise<string>;
+
+export function UpdateConfig(arg1:main.Config):Promise<void>;
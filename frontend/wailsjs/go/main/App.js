@@ -6,6 +6,10 @@ export function AutoDetectAndSaveLoginURL() {
   return window['go']['main']['App']['AutoDetectAndSaveLoginURL']();
 }
 
+export function CancelOperation(arg1) {
+  return window['go']['main']['App']['CancelOperation'](arg1);
+}
+
 export function DetectNetworkLoginPage() {
   return window['go']['main']['App']['DetectNetworkLoginPage']();
 }
@@ -18,26 +22,78 @@ export function EnableAutoStart() {
   return window['go']['main']['App']['EnableAutoStart']();
 }
 
+export function GetBrowser() {
+  return window['go']['main']['App']['GetBrowser']();
+}
+
+export function GetConfig() {
+  return window['go']['main']['App']['GetConfig']();
+}
+
+export function GetLoginSteps() {
+  return window['go']['main']['App']['GetLoginSteps']();
+}
+
 export function GetNetworkStatus() {
   return window['go']['main']['App']['GetNetworkStatus']();
 }
 
+export function GetSessionInfo() {
+  return window['go']['main']['App']['GetSessionInfo']();
+}
+
+export function IsAutoStartEnabled() {
+  return window['go']['main']['App']['IsAutoStartEnabled']();
+}
+
+export function ListOperations() {
+  return window['go']['main']['App']['ListOperations']();
+}
+
+export function ListOperators() {
+  return window['go']['main']['App']['ListOperators']();
+}
+
+export function ListProfiles() {
+  return window['go']['main']['App']['ListProfiles']();
+}
+
+export function ListRecipes() {
+  return window['go']['main']['App']['ListRecipes']();
+}
+
 export function LoginWithAdvancedOptions(arg1, arg2) {
   return window['go']['main']['App']['LoginWithAdvancedOptions'](arg1, arg2);
 }
 
+export function LoginWithProfile(arg1) {
+  return window['go']['main']['App']['LoginWithProfile'](arg1);
+}
+
 export function Loginyzu() {
   return window['go']['main']['App']['Loginyzu']();
 }
 
-export function ReadData() {
-  return window['go']['main']['App']['ReadData']();
+export function Logout() {
+  return window['go']['main']['App']['Logout']();
+}
+
+export function RemoveProfile(arg1) {
+  return window['go']['main']['App']['RemoveProfile'](arg1);
 }
 
-export function SaveValue(arg1) {
-  return window['go']['main']['App']['SaveValue'](arg1);
+export function StartLogin() {
+  return window['go']['main']['App']['StartLogin']();
+}
+
+export function SwitchProfile(arg1) {
+  return window['go']['main']['App']['SwitchProfile'](arg1);
 }
 
 export function TestConnection() {
   return window['go']['main']['App']['TestConnection']();
 }
+
+export function UpdateConfig(arg1) {
+  return window['go']['main']['App']['UpdateConfig'](arg1);
+}
@@ -0,0 +1,376 @@
+export namespace main {
+	
+	export class FailoverStep {
+	    profile: string;
+	    operator?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FailoverStep(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profile = source["profile"];
+	        this.operator = source["operator"];
+	    }
+	}
+	export class Profile {
+	    name: string;
+	    webindex: string;
+	    countindex: string;
+	    passwordindex?: string;
+	    operatorindex: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.webindex = source["webindex"];
+	        this.countindex = source["countindex"];
+	        this.passwordindex = source["passwordindex"];
+	        this.operatorindex = source["operatorindex"];
+	    }
+	}
+	export class RecipeStep {
+	    name: string;
+	    description?: string;
+	    action: string;
+	    selectors?: string[];
+	    open?: string[];
+	    value?: string;
+	    key?: string;
+	    text?: string;
+	    script?: string;
+	    max_retries?: number;
+	    timeout?: string;
+	    delay?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecipeStep(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.action = source["action"];
+	        this.selectors = source["selectors"];
+	        this.open = source["open"];
+	        this.value = source["value"];
+	        this.key = source["key"];
+	        this.text = source["text"];
+	        this.script = source["script"];
+	        this.max_retries = source["max_retries"];
+	        this.timeout = source["timeout"];
+	        this.delay = source["delay"];
+	    }
+	}
+	export class Recipe {
+	    name: string;
+	    description?: string;
+	    match?: string[];
+	    vendor?: string;
+	    steps: RecipeStep[];
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Recipe(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.match = source["match"];
+	        this.vendor = source["vendor"];
+	        this.steps = this.convertValues(source["steps"], RecipeStep);
+	        this.source = source["source"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Config {
+	    version: number;
+	    webindex?: string;
+	    countindex?: string;
+	    passwordindex?: string;
+	    operatorindex?: string;
+	    autostartindex: boolean;
+	    driver: string;
+	    watchdog: boolean;
+	    default_profile: string;
+	    profiles: Profile[];
+	    failover?: FailoverStep[];
+	    recipe?: string;
+	    browser_idle_timeout?: number;
+	    browser_path?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Config(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.webindex = source["webindex"];
+	        this.countindex = source["countindex"];
+	        this.passwordindex = source["passwordindex"];
+	        this.operatorindex = source["operatorindex"];
+	        this.autostartindex = source["autostartindex"];
+	        this.driver = source["driver"];
+	        this.watchdog = source["watchdog"];
+	        this.default_profile = source["default_profile"];
+	        this.profiles = this.convertValues(source["profiles"], Profile);
+	        this.failover = this.convertValues(source["failover"], FailoverStep);
+	        this.recipe = source["recipe"];
+	        this.browser_idle_timeout = source["browser_idle_timeout"];
+	        this.browser_path = source["browser_path"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class StepResult {
+	    name: string;
+	    attempts: number;
+	    duration_ms: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StepResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.attempts = source["attempts"];
+	        this.duration_ms = source["duration_ms"];
+	        this.error = source["error"];
+	    }
+	}
+	export class LoginAttempt {
+	    profile: string;
+	    operator: string;
+	    outcome: string;
+	    reason?: string;
+	    error?: string;
+	    duration_ms: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoginAttempt(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.profile = source["profile"];
+	        this.operator = source["operator"];
+	        this.outcome = source["outcome"];
+	        this.reason = source["reason"];
+	        this.error = source["error"];
+	        this.duration_ms = source["duration_ms"];
+	    }
+	}
+	export class LoginResult {
+	    outcome: string;
+	    profile: string;
+	    operator: string;
+	    driver: string;
+	    steps: StepResult[];
+	    duration_ms: number;
+	    final_url: string;
+	    message: string;
+	    ip: string;
+	    retries: number;
+	    error?: string;
+	    reason?: string;
+	    fallback: number;
+	    attempts?: LoginAttempt[];
+	    recipe?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LoginResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.outcome = source["outcome"];
+	        this.profile = source["profile"];
+	        this.operator = source["operator"];
+	        this.driver = source["driver"];
+	        this.steps = this.convertValues(source["steps"], StepResult);
+	        this.duration_ms = source["duration_ms"];
+	        this.final_url = source["final_url"];
+	        this.message = source["message"];
+	        this.ip = source["ip"];
+	        this.retries = source["retries"];
+	        this.error = source["error"];
+	        this.reason = source["reason"];
+	        this.fallback = source["fallback"];
+	        this.attempts = this.convertValues(source["attempts"], LoginAttempt);
+	        this.recipe = source["recipe"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Operator {
+	    id: string;
+	    label: string;
+	    value: string;
+	    name?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Operator(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.label = source["label"];
+	        this.value = source["value"];
+	        this.name = source["name"];
+	    }
+	}
+	export class SessionDetail {
+	    name: string;
+	    value: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionDetail(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.value = source["value"];
+	    }
+	}
+	export class SessionInfo {
+	    online: boolean;
+	    user_name: string;
+	    user_id: string;
+	    ip: string;
+	    mac: string;
+	    service: string;
+	    online_time: string;
+	    used_traffic: string;
+	    balance: string;
+	    details: SessionDetail[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.online = source["online"];
+	        this.user_name = source["user_name"];
+	        this.user_id = source["user_id"];
+	        this.ip = source["ip"];
+	        this.mac = source["mac"];
+	        this.service = source["service"];
+	        this.online_time = source["online_time"];
+	        this.used_traffic = source["used_traffic"];
+	        this.balance = source["balance"];
+	        this.details = this.convertValues(source["details"], SessionDetail);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BrowserInfo {
+	    path: string;
+	    source: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BrowserInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.source = source["source"];
+	    }
+	}
+	export class OperationInfo {
+	    id: string;
+	    kind: string;
+	    started_at: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new OperationInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.kind = source["kind"];
+	        this.started_at = source["started_at"];
+	    }
+	}
+
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EPortalClient 锐捷 ePortal 协议客户端，直接通过 HTTP 完成认证，无需启动浏览器
+type EPortalClient struct {
+	loginURL    *url.URL
+	queryString string
+	httpClient  *http.Client
+}
+
+// ePortalResponse InterFace.do 接口返回的 JSON 结构
+type ePortalResponse struct {
+	UserIndex         string `json:"userIndex"`
+	Result            string `json:"result"`
+	Message           string `json:"message"`
+	ForwordURL        string `json:"forwordurl"`
+	KeepaliveInterval int    `json:"keepaliveInterval"`
+	ValidCodeURL      string `json:"validCodeUrl"`
+}
+
+// portalTransport 认证相关请求共用的连接池，避免每个客户端各自保留空闲连接。
+// 与浏览器的 no-proxy-server 保持一致，认证请求不走代理
+var portalTransport = &http.Transport{
+	Proxy:           nil,
+	IdleConnTimeout: 30 * time.Second,
+}
+
+// NewEPortalClient 根据校园网跳转生成的登录链接创建客户端
+func NewEPortalClient(loginURL string, timeout time.Duration) (*EPortalClient, error) {
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析登录链接失败: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("登录链接不完整: %s", loginURL)
+	}
+	if u.RawQuery == "" {
+		return nil, fmt.Errorf("登录链接缺少查询参数，请复制校园网跳转生成的完整链接")
+	}
+
+	return &EPortalClient{
+		loginURL:    u,
+		queryString: u.RawQuery,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: portalTransport,
+		},
+	}, nil
+}
+
+// interfaceURL 拼接 InterFace.do 接口地址，与登录页位于同一目录
+func (c *EPortalClient) interfaceURL(method string) string {
+	ref := &url.URL{Path: "InterFace.do", RawQuery: "method=" + method}
+	return c.loginURL.ResolveReference(ref).String()
+}
+
+// post 调用 InterFace.do 接口并解析返回结果
+func (c *EPortalClient) post(ctx context.Context, method string, form url.Values) (*ePortalResponse, error) {
+	var result ePortalResponse
+	if err := c.postJSON(ctx, method, form, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// postJSON 调用 InterFace.do 接口并将返回的 JSON 解析到 v
+func (c *EPortalClient) postJSON(ctx context.Context, method string, form url.Values, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.interfaceURL(method), strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+	req.Header.Set("Referer", c.loginURL.String())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 %s 失败: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求 %s 返回状态码 %d", method, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	return nil
+}
+
+// Login 提交账号、密码和运营商服务完成认证
+func (c *EPortalClient) Login(ctx context.Context, username, password, service string) (*ePortalResponse, error) {
+	form := url.Values{}
+	form.Set("userId", username)
+	form.Set("password", password)
+	form.Set("service", service)
+	form.Set("queryString", c.queryString)
+	form.Set("operatorPwd", "")
+	form.Set("operatorUserId", "")
+	form.Set("validcode", "")
+	form.Set("passwordEncrypt", "false")
+
+	result, err := c.post(ctx, "login", form)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Result != "success" {
+		return result, portalFailure(result.Message)
+	}
+
+	return result, nil
+}
+
+// Logout 注销 userIndex 对应的在线用户
+func (c *EPortalClient) Logout(ctx context.Context, userIndex string) (*ePortalResponse, error) {
+	form := url.Values{}
+	form.Set("userIndex", userIndex)
+
+	result, err := c.post(ctx, "logout", form)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Result != "success" {
+		return result, fmt.Errorf("注销失败: %s", result.Message)
+	}
+
+	return result, nil
+}
+
+// ePortalUserInfo getOnlineUserInfo 接口返回的在线用户信息
+type ePortalUserInfo struct {
+	ePortalResponse
+	UserName   string `json:"userName"`
+	UserID     string `json:"userId"`
+	UserIP     string `json:"userIp"`
+	UserMAC    string `json:"userMac"`
+	Service    string `json:"service"`
+	AccountFee string `json:"accountFee"`
+	BallInfo   string `json:"ballInfo"`
+}
+
+// OnlineUserInfo 查询在线用户信息，userIndex 为空时由认证系统按本机地址查询，未登录时返回 ErrNotOnline
+func (c *EPortalClient) OnlineUserInfo(ctx context.Context, userIndex string) (*ePortalUserInfo, error) {
+	form := url.Values{}
+	form.Set("userIndex", userIndex)
+
+	var result ePortalUserInfo
+	if err := c.postJSON(ctx, "getOnlineUserInfo", form, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Result != "success" || result.UserIndex == "" {
+		return nil, ErrNotOnline
+	}
+
+	return &result, nil
+}
+
+// OnlineUserIndex 查询本机当前在线用户的 userIndex，未登录时返回 ErrNotOnline
+func (c *EPortalClient) OnlineUserIndex(ctx context.Context) (string, error) {
+	info, err := c.OnlineUserInfo(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	return info.UserIndex, nil
+}
+
+// Probe 请求登录页，确认其为 ePortal 认证页面
+func (c *EPortalClient) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.loginURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("访问登录页面失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("登录页面返回状态码 %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取登录页面失败: %w", err)
+	}
+
+	if !strings.Contains(string(body), "InterFace.do") && !strings.Contains(c.loginURL.Path, "eportal") {
+		return fmt.Errorf("页面不是 ePortal 认证页面")
+	}
+
+	return nil
+}
+
+// operatorService 返回提交给认证接口的服务名称：优先使用认证页面上按名称匹配到的服务，
+// 读取不到服务列表时使用标准名称
+func (c *EPortalClient) operatorService(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("未选择运营商")
+	}
+
+	operators, err := c.Operators(ctx)
+	if err != nil || len(operators) == 0 {
+		log.Printf("未能读取运营商列表，直接提交 %s: %v", canonicalOperatorName(name), err)
+		return canonicalOperatorName(name), nil
+	}
+
+	operator, ok := matchOperator(operators, name)
+	if !ok {
+		return "", &PortalError{Kind: ErrOperatorUnavailable, Message: fmt.Sprintf("认证页面没有运营商 %s", name)}
+	}
+	return operator.Value, nil
+}
+
+// lastUserIndex 最近一次登录成功时认证系统分配的 userIndex，注销时使用
+var (
+	userIndexMu   sync.Mutex
+	lastUserIndex string
+)
+
+// rememberUserIndex 记录登录成功后的 userIndex，注销后传入空字符串清除
+func rememberUserIndex(userIndex string) {
+	userIndexMu.Lock()
+	defer userIndexMu.Unlock()
+	lastUserIndex = userIndex
+}
+
+// rememberedUserIndex 返回记录的 userIndex
+func rememberedUserIndex() string {
+	userIndexMu.Lock()
+	defer userIndexMu.Unlock()
+	return lastUserIndex
+}
+
+// httpDriver 通过 ePortal HTTP 协议登录的驱动
+type httpDriver struct{}
+
+func init() {
+	RegisterLoginDriver("http", func() LoginDriver { return &httpDriver{} })
+}
+
+func (d *httpDriver) Login(ctx context.Context, config *Config) (*LoginResult, error) {
+	return loginWithHTTP(ctx, config)
+}
+
+func (d *httpDriver) Probe(ctx context.Context, config *Config) (string, error) {
+	client, err := NewEPortalClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	if err := client.Probe(ctx); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("连接测试结果:\n- 页面加载: 成功\n- 认证接口: %s", client.interfaceURL("login")), nil
+}
+
+func (d *httpDriver) Logout(ctx context.Context, config *Config) (*LoginResult, error) {
+	return logoutWithHTTP(ctx, config)
+}
+
+// loginWithHTTP 通过 HTTP 协议直接登录，认证接口返回 success 即视为已确认
+func loginWithHTTP(ctx context.Context, config *Config) (*LoginResult, error) {
+	log.Println("尝试通过 HTTP 协议直接登录...")
+	start := time.Now()
+	result := newLoginResult("http", config)
+
+	client, err := NewEPortalClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	service, err := client.operatorService(ctx, config.Operatorindex)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	var response *ePortalResponse
+	err = result.runStep(ctx, "提交认证", func() (err error) {
+		response, err = client.Login(ctx, config.Countindex, config.Passwordindex, service)
+		return err
+	})
+	result.FinalURL = client.interfaceURL("login")
+	if response != nil {
+		result.Message = response.Message
+		if response.ForwordURL != "" {
+			result.FinalURL = response.ForwordURL
+		}
+	}
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	result.Outcome = LoginOutcomeSuccess
+	rememberUserIndex(response.UserIndex)
+	log.Printf("HTTP 登录成功，userIndex: %s", response.UserIndex)
+	return result, result.finish(start, nil)
+}
+
+// logoutWithHTTP 通过 HTTP 协议注销，查询不到在线用户时使用登录时记录的 userIndex
+func logoutWithHTTP(ctx context.Context, config *Config) (*LoginResult, error) {
+	log.Println("尝试通过 HTTP 协议注销...")
+	start := time.Now()
+	result := newLoginResult("http", config)
+
+	client, err := NewEPortalClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	var userIndex string
+	err = result.runStep(ctx, "查询在线用户", func() (err error) {
+		userIndex, err = client.OnlineUserIndex(ctx)
+		if err != nil && rememberedUserIndex() != "" {
+			// 部分认证系统不支持查询在线用户
+			log.Printf("查询在线用户失败，使用登录时记录的 userIndex: %v", err)
+			userIndex, err = rememberedUserIndex(), nil
+		}
+		return err
+	})
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	var response *ePortalResponse
+	err = result.runStep(ctx, "提交注销", func() (err error) {
+		response, err = client.Logout(ctx, userIndex)
+		return err
+	})
+	result.FinalURL = client.interfaceURL("logout")
+	if response != nil {
+		result.Message = response.Message
+	}
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	result.Outcome = LoginOutcomeSuccess
+	rememberUserIndex("")
+	log.Printf("HTTP 注销成功，userIndex: %s", userIndex)
+	return result, result.finish(start, nil)
+}
@@ -1,115 +1,159 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
-	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/launcher"
-	"github.com/go-rod/rod/lib/proto"
 )
 
-// 定义一个结构体来表示 JSON 数据的结构
-type Config struct {
-	Autostartindex string `json:"autostartindex"`
-	Countindex     string `json:"countindex"`
-	Operatorindex  string `json:"operatorindex"`
-	Passwordindex  string `json:"passwordindex"`
-	Webindex       string `json:"webindex"`
+// Loginyzu 使用默认账号与配置的登录驱动执行自动登录，返回结构化的登录结果。
+// 执行期间可通过 ListOperations 查到并取消，需要立即拿到操作 ID 时使用 StartLogin
+func (a *App) Loginyzu() (*LoginResult, error) {
+	ctx, done := a.track(OperationLogin)
+	defer done()
+	return a.login(ctx, "")
 }
 
-// 读取 JSON 文件并解码
-func ReadConfig(filename string) (*Config, error) {
-	exePath, err := os.Executable()
+// LoginWithProfile 使用指定账号登录，不改变默认账号
+func (a *App) LoginWithProfile(name string) (*LoginResult, error) {
+	ctx, done := a.track(OperationLogin)
+	defer done()
+	return a.login(ctx, name)
+}
+
+// watchdogLogin 网络守护掉线后重新登录，停止守护时 ctx 被取消，进行中的登录随之中止。
+// 登录同样登记为进行中的操作，可通过 CancelOperation 取消
+func (a *App) watchdogLogin(ctx context.Context) (*LoginResult, error) {
+	id, ctx, done := a.operations.Start(WithBrowserService(ctx, a.browsers), OperationLogin)
+	defer done()
+	return a.login(a.withStepEvents(ctx, id), "")
+}
+
+// login 执行自动登录，profile 为空时使用默认账号，ctx 结束时中止登录
+func (a *App) login(ctx context.Context, profile string) (*LoginResult, error) {
+	// 避免手动登录与网络守护同时执行，排队时取消操作可立即返回
+	release, err := a.acquireLogin(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get executable path: %w", err)
+		return nil, err
 	}
-	exeDir := filepath.Dir(exePath)
-	fmt.Println("Executable directory:", exeDir)
-	filename = filepath.Join(exeDir, filename)
-	// 打开 JSON 文件
-	file, err := os.Open(filename)
+	defer release()
+
+	log.Println("开始执行自动登录流程")
+
+	// 读取配置
+	config, err := LoadConfig()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error reading config: %w", err)
 	}
-	defer file.Close()
 
-	// 创建 JSON 解码器
-	decoder := json.NewDecoder(file)
+	// 只记录认证地址、驱动与账号名，配置中的学号与密码不写入日志
+	logProfile := profile
+	if logProfile == "" {
+		logProfile = config.DefaultProfile
+	}
+	log.Printf("配置信息: 认证地址: %s, 驱动: %s, 账号: %s", config.Webindex, config.Driver, logProfile)
 
-	// 解码 JSON 数据到结构体中
-	var config Config
-	if err := decoder.Decode(&config); err != nil {
-		return nil, err
+	var result *LoginResult
+	if profile == "" {
+		// 默认账号登录失败时按故障转移策略尝试其他账号与运营商
+		result, err = loginWithFailover(ctx, config)
+	} else {
+		result, err = loginFailoverStep(ctx, config, FailoverStep{Profile: profile})
+	}
+	if err != nil {
+		return result, err
+	}
+
+	// 手动注销后暂停的网络守护在重新登录后恢复
+	if a.watchdog != nil {
+		a.watchdog.Resume()
 	}
 
-	return &config, nil
+	log.Printf("自动登录流程执行完成: %s (账号: %s, 运营商: %s, 驱动: %s, 耗时: %dms, 重试: %d, 备用步骤: %d)",
+		result.Outcome, result.Profile, result.Operator, result.Driver, result.DurationMs, result.Retries, result.Fallback)
+	return result, nil
 }
 
-func (a *App) Loginyzu() error {
-	log.Println("开始执行自动登录流程")
+// rodDriver 通过 go-rod 模拟浏览器操作的登录驱动
+type rodDriver struct{}
+
+func init() {
+	RegisterLoginDriver("rod", func() LoginDriver { return &rodDriver{} })
+}
+
+func (d *rodDriver) Login(ctx context.Context, config *Config) (*LoginResult, error) {
+	return loginWithRod(ctx, config)
+}
+
+func (d *rodDriver) Probe(ctx context.Context, config *Config) (string, error) {
+	return probeWithRod(ctx, config)
+}
+
+func (d *rodDriver) Logout(ctx context.Context, config *Config) (*LoginResult, error) {
+	return logoutWithRod(ctx, config)
+}
 
-	// 读取 JSON 文件
-	config, err := ReadConfig("data.json")
+// openBrowserPage 从 ctx 中的浏览器服务租用浏览器并打开页面，返回的 cleanup 负责关闭页面并归还浏览器。
+// 页面绑定 ctx，ctx 结束时页面上的操作中止，页面随租约关闭
+func openBrowserPage(ctx context.Context, targetURL string, headless bool) (*rod.Page, func(), error) {
+	service, err := browserServiceFrom(ctx)
 	if err != nil {
-		return fmt.Errorf("error reading config: %w", err)
+		return nil, nil, err
+	}
+	lease, err := service.Acquire(ctx, headless)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 打印读取到的配置
-	log.Printf("配置信息: %+v", config)
-
-	// 启动浏览器
-	log.Println("正在启动浏览器...")
-	launcher := launcher.New().Headless(false).Set("no-proxy-server")
-	controlURL, err := launcher.Launch()
+	page, err := lease.Page(targetURL)
 	if err != nil {
-		launcher.Kill()
-		return fmt.Errorf("browser launch failed: %w", err)
+		lease.Release()
+		return nil, nil, err
 	}
 
-	// 确保在函数结束时清理资源
-	defer func() {
-		log.Println("清理浏览器资源...")
-		launcher.Kill()
-	}()
+	return page, lease.Release, nil
+}
 
-	browser := rod.New().ControlURL(controlURL)
-	if err := browser.Connect(); err != nil {
-		return fmt.Errorf("browser connection failed: %w", err)
-	}
+// loginWithRod 通过 go-rod 模拟浏览器操作登录
+func loginWithRod(ctx context.Context, config *Config) (*LoginResult, error) {
+	start := time.Now()
+	result := newLoginResult("rod", config)
 
-	page, err := browser.Page(proto.TargetCreateTarget{URL: config.Webindex})
+	page, cleanup, err := openBrowserPage(ctx, config.Webindex, false)
 	if err != nil {
-		return fmt.Errorf("page creation failed: %w", err)
+		return result, result.finish(start, err)
 	}
+	defer cleanup()
 
-	// 确保在函数结束时关闭页面
-	defer func() {
-		if err := page.Close(); err != nil {
-			log.Printf("关闭页面时出错: %v", err)
-		}
-	}()
+	// 在点击登录之前开始监听弹窗，认证页面可能用 alert 提示错误
+	inspector := NewPortalInspector(page)
+	defer inspector.Close()
 
-	// 获取登录步骤
-	steps := GetLoginSteps()
+	// 登录步骤由登录脚本描述，其他学校的认证页面放入自定义脚本即可
+	recipe, err := configRecipe(config)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+	result.Recipe = recipe.Name
+	log.Printf("使用登录脚本: %s (%s)", recipe.Name, recipe.Source)
+	steps := recipe.LoginSteps()
 
 	// 执行所有登录步骤
 	for _, step := range steps {
-		if err := ExecuteLoginStep(page, config, step); err != nil {
-			return fmt.Errorf("登录流程在 '%s' 步骤失败: %w", step.Name, err)
+		stepResult, err := ExecuteLoginStep(ctx, page, config, step)
+		result.addStep(stepResult)
+		if err != nil {
+			return result, result.finish(start, fmt.Errorf("登录流程在 '%s' 步骤失败: %w", step.Name, err))
 		}
 	}
 
-	// 减少登录完成等待时间
+	// 等待认证结果，而不是固定等待后直接视为成功
 	log.Println("等待登录完成...")
-	time.Sleep(2 * time.Second)
-
-	log.Println("自动登录流程执行完成")
-	return nil
+	err = inspector.Verify(result)
+	return result, result.finish(start, err)
 }
 
 // LoginWithAdvancedOptions 提供更高级的登录选项
@@ -121,7 +165,8 @@ func (a *App) LoginWithAdvancedOptions(enableDebug bool, customTimeout int) erro
 	}
 
 	// 调用原始登录函数
-	return a.Loginyzu()
+	_, err := a.Loginyzu()
+	return err
 }
 
 // TestConnection 测试连接功能，不实际登录
@@ -129,67 +174,47 @@ func (a *App) TestConnection() (string, error) {
 	log.Println("执行连接测试...")
 
 	// 读取配置
-	config, err := ReadConfig("data.json")
+	config, err := LoadConfig()
 	if err != nil {
 		return "", fmt.Errorf("读取配置失败: %w", err)
 	}
 
-	// 启动浏览器进行测试
-	launcher := launcher.New().Headless(true).Set("no-proxy-server")
-	controlURL, err := launcher.Launch()
+	driver, err := NewLoginDriver(config.Driver)
 	if err != nil {
-		return "", fmt.Errorf("浏览器启动失败: %w", err)
+		return "", err
 	}
-	defer launcher.Kill()
 
-	browser := rod.New().ControlURL(controlURL)
-	if err := browser.Connect(); err != nil {
-		return "", fmt.Errorf("浏览器连接失败: %w", err)
-	}
+	ctx, done := a.track(OperationProbe)
+	defer done()
+	return driver.Probe(ctx, config)
+}
+
+// probeTimeout 浏览器检测登录页面的总超时
+const probeTimeout = 30 * time.Second
 
-	page, err := browser.Page(proto.TargetCreateTarget{URL: config.Webindex})
+// probeWithRod 启动浏览器检查登录页面的关键元素
+func probeWithRod(ctx context.Context, config *Config) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	// 启动浏览器进行测试
+	page, cleanup, err := openBrowserPage(ctx, config.Webindex, true)
 	if err != nil {
-		return "", fmt.Errorf("页面创建失败: %w", err)
+		return "", err
 	}
-	defer page.Close()
+	defer cleanup()
 
 	// 等待页面加载
-	waiter := NewSmartWaiter(page)
+	waiter := NewSmartWaiter(ctx, page)
 	if err := waiter.WaitForPageLoad(10 * time.Second); err != nil {
 		return "", fmt.Errorf("页面加载超时: %w", err)
 	}
 
 	// 检查关键元素是否存在
-	usernameFound := false
-	passwordFound := false
-
-	selectors := []ElementSelector{
-		{
-			Primary:      "input[name='username']",
-			Alternatives: []string{"input[name='username_tip']", "input[type='text']"},
-		},
-	}
-
-	for _, selector := range selectors {
-		if _, err := waiter.FindElementRobust(selector); err == nil {
-			usernameFound = true
-			break
-		}
-	}
-
-	passwordSelectors := []ElementSelector{
-		{
-			Primary:      "input[type='password']",
-			Alternatives: []string{"input[name='password']", "input[name='pwd_tip']"},
-		},
-	}
-
-	for _, selector := range passwordSelectors {
-		if _, err := waiter.FindElementRobust(selector); err == nil {
-			passwordFound = true
-			break
-		}
-	}
+	_, err = waiter.FindElementRobust(usernameFieldSelector)
+	usernameFound := err == nil
+	_, err = waiter.FindElementRobust(passwordFieldSelector)
+	passwordFound := err == nil
 
 	result := fmt.Sprintf("连接测试结果:\n- 页面加载: 成功\n- 用户名输入框: %v\n- 密码输入框: %v", 
 		map[bool]string{true: "找到", false: "未找到"}[usernameFound],
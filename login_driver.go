@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// LoginDriver 登录驱动，封装一种具体的认证方式。
+// ctx 结束时浏览器操作与 HTTP 请求立即中止
+type LoginDriver interface {
+	// Login 使用配置中的账号完成认证，失败时也会返回已记录的结果
+	Login(ctx context.Context, config *Config) (*LoginResult, error)
+	// Probe 检测登录页面是否可用，不实际登录
+	Probe(ctx context.Context, config *Config) (string, error)
+	// Logout 注销当前在线用户，结果与登录使用相同的结构
+	Logout(ctx context.Context, config *Config) (*LoginResult, error)
+}
+
+// defaultLoginDriver 配置未指定驱动时使用的驱动名称
+const defaultLoginDriver = "auto"
+
+// ErrLogoutNotSupported 驱动不支持注销时返回
+var ErrLogoutNotSupported = errors.New("当前登录驱动不支持注销")
+
+// ErrNotOnline 注销时认证页面没有在线的用户
+var ErrNotOnline = errors.New("当前没有在线的认证用户")
+
+// loginDrivers 已注册的登录驱动
+var loginDrivers = map[string]func() LoginDriver{}
+
+// RegisterLoginDriver 注册登录驱动，重复注册同名驱动会覆盖之前的实现
+func RegisterLoginDriver(name string, factory func() LoginDriver) {
+	loginDrivers[name] = factory
+}
+
+// NewLoginDriver 按名称创建登录驱动，名称为空时使用默认驱动
+func NewLoginDriver(name string) (LoginDriver, error) {
+	if name == "" {
+		name = defaultLoginDriver
+	}
+
+	factory, ok := loginDrivers[name]
+	if !ok {
+		return nil, fmt.Errorf("未知的登录驱动: %s (可用: %v)", name, LoginDriverNames())
+	}
+
+	return factory(), nil
+}
+
+// LoginDriverNames 返回所有已注册的驱动名称
+func LoginDriverNames() []string {
+	names := make([]string, 0, len(loginDrivers))
+	for name := range loginDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// autoDriver 先识别认证系统：其他厂商使用对应的驱动，锐捷与无法识别的页面
+// 优先使用 HTTP 协议，失败时回退到浏览器模拟
+type autoDriver struct {
+	primary  LoginDriver
+	fallback LoginDriver
+}
+
+func init() {
+	RegisterLoginDriver("auto", func() LoginDriver {
+		return &autoDriver{primary: &httpDriver{}, fallback: &rodDriver{}}
+	})
+}
+
+// portalDetectTimeout auto 驱动识别认证系统的超时
+const portalDetectTimeout = 5 * time.Second
+
+// vendorDriver 识别认证系统并返回对应厂商的驱动，沿用默认方式时返回 nil
+func (d *autoDriver) vendorDriver(ctx context.Context, config *Config) LoginDriver {
+	if config.Webindex == "" {
+		return nil
+	}
+	fingerprint, err := DetectPortal(ctx, config.Webindex, portalDetectTimeout)
+	if err != nil {
+		log.Printf("识别认证系统失败: %v", err)
+		return nil
+	}
+
+	name := vendorDriverName(fingerprint)
+	if name == "" {
+		return nil
+	}
+	driver, err := NewLoginDriver(name)
+	if err != nil {
+		log.Printf("创建 %s 驱动失败: %v", name, err)
+		return nil
+	}
+	log.Printf("认证系统为 %s，使用 %s 驱动", fingerprint.Name, name)
+	return driver
+}
+
+func (d *autoDriver) Login(ctx context.Context, config *Config) (*LoginResult, error) {
+	if driver := d.vendorDriver(ctx, config); driver != nil {
+		return driver.Login(ctx, config)
+	}
+
+	result, err := d.primary.Login(ctx, config)
+	if err == nil {
+		return result, nil
+	}
+	if isPermanentLoginError(err) || ctx.Err() != nil {
+		// 认证页面已明确拒绝，换用浏览器模拟也不会成功；操作已取消时也不再回退
+		return result, err
+	}
+
+	log.Printf("HTTP 登录失败，回退到浏览器模拟登录: %v", err)
+	fallback, err := d.fallback.Login(ctx, config)
+	if fallback != nil && result != nil {
+		// 回退本身计为一次重试，并保留 HTTP 尝试的耗时
+		fallback.Retries += result.Retries + 1
+		fallback.DurationMs += result.DurationMs
+	}
+	return fallback, err
+}
+
+func (d *autoDriver) Probe(ctx context.Context, config *Config) (string, error) {
+	if driver := d.vendorDriver(ctx, config); driver != nil {
+		return driver.Probe(ctx, config)
+	}
+
+	result, err := d.primary.Probe(ctx, config)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		log.Printf("HTTP 检测失败，回退到浏览器检测: %v", err)
+		return d.fallback.Probe(ctx, config)
+	}
+	return result, nil
+}
+
+func (d *autoDriver) Logout(ctx context.Context, config *Config) (*LoginResult, error) {
+	if driver := d.vendorDriver(ctx, config); driver != nil {
+		return driver.Logout(ctx, config)
+	}
+
+	result, err := d.primary.Logout(ctx, config)
+	if err == nil || errors.Is(err, ErrNotOnline) || ctx.Err() != nil {
+		return result, err
+	}
+
+	log.Printf("HTTP 注销失败，回退到浏览器注销: %v", err)
+	fallback, err := d.fallback.Logout(ctx, config)
+	if fallback != nil && result != nil {
+		fallback.Retries += result.Retries + 1
+		fallback.DurationMs += result.DurationMs
+	}
+	return fallback, err
+}
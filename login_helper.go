@@ -1,13 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/input"
 	"github.com/go-rod/rod/lib/proto"
 )
 
@@ -19,50 +19,121 @@ type ElementSelector struct {
 	TextContains []string // 可能包含的文本
 }
 
+// usernameFieldSelector 用户名输入框的查找策略，浏览器检测与表单驱动共用
+var usernameFieldSelector = ElementSelector{
+	Primary: "input[name='username']",
+	Alternatives: []string{
+		"input[name='username_tip']",
+		"input[type='text']",
+		"input[id*='username']",
+		"input[class*='username']",
+		"input[placeholder*='用户']",
+		"input[placeholder*='账号']",
+		"input[placeholder*='学号']",
+		"input[placeholder*='Username']",
+		"input[placeholder*='Account']",
+		"#username",
+		".username",
+	},
+	Attributes:   []string{"placeholder", "name", "id", "class"},
+	TextContains: []string{"用户名", "账号", "学号", "username", "account", "user"},
+}
+
+// passwordFieldSelector 密码输入框的查找策略
+var passwordFieldSelector = ElementSelector{
+	Primary: "input[type='password']",
+	Alternatives: []string{
+		"input[name='password']",
+		"input[name='pwd_tip']",
+		"input[id*='password']",
+		"input[class*='password']",
+		"input[placeholder*='密码']",
+		"input[placeholder*='Password']",
+		"#password",
+		".password",
+	},
+	Attributes:   []string{"name", "id", "class", "placeholder"},
+	TextContains: []string{"密码", "password", "pass"},
+}
+
 // LoginStep 定义登录步骤
 type LoginStep struct {
 	Name        string
 	Description string
-	Execute     func(*rod.Page, *Config) error
-	MaxRetries  int
-	Timeout     time.Duration
+	// Execute 执行步骤，page 已绑定 ctx，ctx 在单次尝试超时或操作取消时结束
+	Execute    func(context.Context, *rod.Page, *Config) error
+	MaxRetries int
+	// Timeout 单次尝试的超时，重试间隔为其四分之一
+	Timeout time.Duration
+	// Delay 每次尝试前等待的时间，不计入超时
+	Delay time.Duration
 }
 
 // SmartWaiter 智能等待器
 type SmartWaiter struct {
+	ctx  context.Context
 	page *rod.Page
 }
 
-// NewSmartWaiter 创建智能等待器
-func NewSmartWaiter(page *rod.Page) *SmartWaiter {
-	return &SmartWaiter{page: page}
+// NewSmartWaiter 创建智能等待器，ctx 结束时等待与查找随之中止
+func NewSmartWaiter(ctx context.Context, page *rod.Page) *SmartWaiter {
+	return &SmartWaiter{ctx: ctx, page: page.Context(ctx)}
+}
+
+// element 查找元素，找不到时立即返回错误，而不是一直等到 ctx 结束
+func (sw *SmartWaiter) element(selector string) (*rod.Element, error) {
+	return sw.page.Sleeper(rod.NotFoundSleeper).Element(selector)
+}
+
+// elementPollInterval 等待元素出现时的轮询间隔
+const elementPollInterval = 200 * time.Millisecond
+
+// sleepContext 等待指定时间，ctx 先结束时返回其错误
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
-// WaitForElementWithRetry 等待元素出现，支持重试
+// WaitForElementWithRetry 轮询所有选择器直到任一元素出现，最多等待 timeout
 func (sw *SmartWaiter) WaitForElementWithRetry(selectors []string, timeout time.Duration) (*rod.Element, error) {
+	page := sw.page.Timeout(timeout)
+	defer page.CancelTimeout()
+
 	var lastErr error
-	
-	for _, selector := range selectors {
-		element, err := sw.page.Element(selector)
-		if err == nil {
-			return element, nil
+	for {
+		for _, selector := range selectors {
+			element, err := page.Sleeper(rod.NotFoundSleeper).Element(selector)
+			if err == nil {
+				// 元素不再受等待超时的限制
+				return element.Context(sw.ctx), nil
+			}
+			lastErr = err
+		}
+
+		if err := sleepContext(page.GetContext(), elementPollInterval); err != nil {
+			return nil, fmt.Errorf("none of the selectors worked: %v", lastErr)
 		}
-		lastErr = err
 	}
-	
-	return nil, fmt.Errorf("none of the selectors worked: %v", lastErr)
 }
 
-// WaitForPageLoad 智能等待页面加载完成
+// WaitForPageLoad 智能等待页面加载完成，最多等待 timeout
 func (sw *SmartWaiter) WaitForPageLoad(timeout time.Duration) error {
 	// 等待页面完全加载
-	err := sw.page.WaitLoad()
+	err := sw.page.Timeout(timeout).WaitLoad()
 	if err != nil {
 		return fmt.Errorf("page load timeout: %w", err)
 	}
 	
 	// 减少额外等待时间，使用更智能的检测
-	time.Sleep(500 * time.Millisecond)
+	if err := sleepContext(sw.ctx, 500*time.Millisecond); err != nil {
+		return err
+	}
 	
 	// 快速检查页面是否包含关键元素
 	checkSelectors := []string{
@@ -101,12 +172,14 @@ func (sw *SmartWaiter) FindElementRobust(selector ElementSelector) (*rod.Element
 	for retry := 0; retry < maxRetries; retry++ {
 		if retry > 0 {
 			log.Printf("元素查找重试 %d/%d", retry, maxRetries)
-			time.Sleep(200 * time.Millisecond)  // 减少重试间隔
+			if err := sleepContext(sw.ctx, 200*time.Millisecond); err != nil {  // 减少重试间隔
+				return nil, err
+			}
 		}
 		
 		// 尝试主要选择器
 		if selector.Primary != "" {
-			element, err := sw.page.Element(selector.Primary)
+			element, err := sw.element(selector.Primary)
 			if err == nil {
 				log.Printf("通过主要选择器找到元素: %s", selector.Primary)
 				return element, nil
@@ -116,7 +189,7 @@ func (sw *SmartWaiter) FindElementRobust(selector ElementSelector) (*rod.Element
 		
 		// 尝试备选选择器
 		for _, alt := range selector.Alternatives {
-			element, err := sw.page.Element(alt)
+			element, err := sw.element(alt)
 			if err == nil {
 				log.Printf("通过备选选择器找到元素: %s", alt)
 				return element, nil
@@ -140,7 +213,7 @@ func (sw *SmartWaiter) FindElementRobust(selector ElementSelector) (*rod.Element
 		
 		// 尝试通过文本内容查找
 		for _, text := range selector.TextContains {
-			element, err := sw.page.Element(fmt.Sprintf("//*[contains(text(), '%s')]", text))
+			element, err := sw.element(fmt.Sprintf("//*[contains(text(), '%s')]", text))
 			if err == nil {
 				log.Printf("通过文本内容找到元素: 包含 '%s'", text)
 				return element, nil
@@ -174,8 +247,8 @@ func (sw *SmartWaiter) FindElementRobust(selector ElementSelector) (*rod.Element
 	return nil, fmt.Errorf("element not found with any selector: %w", lastErr)
 }
 
-// RetryOperation 带重试的操作
-func RetryOperation(operation func() error, maxRetries int, delay time.Duration) error {
+// RetryOperation 带重试的操作，ctx 结束时不再重试
+func RetryOperation(ctx context.Context, operation func() error, maxRetries int, delay time.Duration) error {
 	var lastErr error
 	
 	for i := 0; i < maxRetries; i++ {
@@ -185,155 +258,81 @@ func RetryOperation(operation func() error, maxRetries int, delay time.Duration)
 		}
 		
 		lastErr = err
+		if isPermanentLoginError(err) {
+			// 密码错误、账号欠费等情况重试无意义
+			return err
+		}
+		if ctx.Err() != nil {
+			// 操作已取消，单次尝试超时不在此列
+			return ctx.Err()
+		}
 		if i < maxRetries-1 {
 			log.Printf("操作失败，%v 后重试 (尝试 %d/%d): %v", delay, i+1, maxRetries, err)
-			time.Sleep(delay)
+			if err := sleepContext(ctx, delay); err != nil {
+				return err
+			}
 		}
 	}
 	
 	return fmt.Errorf("操作在 %d 次尝试后仍然失败: %w", maxRetries, lastErr)
 }
 
-// ExecuteLoginStep 执行登录步骤
-func ExecuteLoginStep(page *rod.Page, config *Config, step LoginStep) error {
+// ExecuteLoginStep 执行登录步骤，返回步骤的尝试次数与耗时。
+// 每次尝试在 step.Timeout 后中止，ctx 结束时整个步骤立即中止
+func ExecuteLoginStep(ctx context.Context, page *rod.Page, config *Config, step LoginStep) (StepResult, error) {
 	log.Printf("执行步骤: %s - %s", step.Name, step.Description)
 	
+	result := StepResult{Name: step.Name}
+	start := time.Now()
+	var lastErr error
 	operation := func() error {
-		return step.Execute(page, config)
+		result.Attempts++
+		event := StepEvent{Step: step.Name, Status: StepStarted, Attempt: result.Attempts}
+		if lastErr != nil {
+			event.Status = StepRetrying
+			event.Error = lastErr.Error()
+		}
+		event.ElapsedMs = time.Since(start).Milliseconds()
+		publishStep(ctx, event)
+
+		if step.Delay > 0 {
+			if err := sleepContext(ctx, step.Delay); err != nil {
+				return err
+			}
+		}
+
+		attemptCtx := ctx
+		if step.Timeout > 0 {
+			var cancel context.CancelFunc
+			attemptCtx, cancel = context.WithTimeout(ctx, step.Timeout)
+			defer cancel()
+		}
+		lastErr = step.Execute(attemptCtx, page.Context(attemptCtx), config)
+		return lastErr
 	}
 	
-	err := RetryOperation(operation, step.MaxRetries, step.Timeout/4)
+	err := RetryOperation(ctx, operation, step.MaxRetries, step.Timeout/4)
+	result.DurationMs = time.Since(start).Milliseconds()
+	event := StepEvent{Step: step.Name, Status: StepSucceeded, Attempt: result.Attempts, ElapsedMs: result.DurationMs}
 	if err != nil {
-		return fmt.Errorf("步骤 '%s' 失败: %w", step.Name, err)
+		result.Error = err.Error()
+		event.Status = StepFailed
+		event.Error = err.Error()
+		publishStep(ctx, event)
+		return result, fmt.Errorf("步骤 '%s' 失败: %w", step.Name, err)
 	}
+	publishStep(ctx, event)
 	
 	log.Printf("步骤 '%s' 成功完成", step.Name)
-	return nil
-}
-
-// GetLoginSteps 获取登录步骤序列
-func GetLoginSteps() []LoginStep {
-	return []LoginStep{
-		{
-			Name:        "等待页面加载",
-			Description: "等待登录页面完全加载",
-			Execute:     waitForPageLoad,
-			MaxRetries:  2,  // 减少重试次数
-			Timeout:     8 * time.Second,  // 减少超时时间
-		},
-		{
-			Name:        "输入用户名",
-			Description: "在用户名输入框中输入账号",
-			Execute:     inputUsername,
-			MaxRetries:  2,  // 减少重试次数
-			Timeout:     3 * time.Second,  // 减少超时时间
-		},
-		{
-			Name:        "输入密码",
-			Description: "在密码输入框中输入密码",
-			Execute:     inputPassword,
-			MaxRetries:  2,  // 减少重试次数
-			Timeout:     3 * time.Second,  // 减少超时时间
-		},
-		{
-			Name:        "提交表单",
-			Description: "提交登录表单",
-			Execute:     submitForm,
-			MaxRetries:  2,
-			Timeout:     3 * time.Second,  // 减少超时时间
-		},
-		{
-			Name:        "选择运营商",
-			Description: "选择网络运营商",
-			Execute:     selectOperator,
-			MaxRetries:  2,  // 减少重试次数
-			Timeout:     3 * time.Second,  // 减少超时时间
-		},
-		{
-			Name:        "确认登录",
-			Description: "点击最终登录按钮",
-			Execute:     confirmLogin,
-			MaxRetries:  2,  // 减少重试次数
-			Timeout:     3 * time.Second,  // 减少超时时间
-		},
-	}
+	return result, nil
 }
 
 // waitForPageLoad 等待页面加载
-func waitForPageLoad(page *rod.Page, config *Config) error {
-	waiter := NewSmartWaiter(page)
+func waitForPageLoad(ctx context.Context, page *rod.Page, config *Config) error {
+	waiter := NewSmartWaiter(ctx, page)
 	return waiter.WaitForPageLoad(10 * time.Second)
 }
 
-// inputUsername 输入用户名
-func inputUsername(page *rod.Page, config *Config) error {
-	waiter := NewSmartWaiter(page)
-	
-	// 减少额外等待时间
-	time.Sleep(300 * time.Millisecond)
-	
-	// 定义多种可能的选择器
-	selectors := []ElementSelector{
-		{
-			Primary:      "input[name='username']",
-			Alternatives: []string{
-				"input[name='username_tip']", 
-				"input[type='text']", 
-				"input[id*='username']", 
-				"input[class*='username']",
-				"input[placeholder*='用户']",
-				"input[placeholder*='账号']",
-				"input[placeholder*='学号']",
-				"input[placeholder*='Username']",
-				"input[placeholder*='Account']",
-				"#username",
-				".username",
-				"input:text",
-			},
-			Attributes:   []string{"placeholder", "name", "id", "class", "type"},
-			TextContains: []string{"用户名", "账号", "学号", "username", "account", "user"},
-		},
-	}
-	
-	// 先尝试查找所有文本输入框
-	elements, err := page.Elements("input[type='text']")
-	if err == nil && len(elements) > 0 {
-		log.Printf("找到 %d 个文本输入框，尝试第一个", len(elements))
-		element := elements[0]
-		
-		// 检查元素是否可见和可点击
-		if err := waitForElementReady(element); err != nil {
-			log.Printf("第一个文本输入框不可用: %v", err)
-		} else {
-			return fillInputElement(element, config.Countindex, "用户名")
-		}
-	}
-	
-	for _, selector := range selectors {
-		element, err := waiter.FindElementRobust(selector)
-		if err == nil {
-			return fillInputElement(element, config.Countindex, "用户名")
-		}
-	}
-	
-	// 最后尝试：查找所有输入框，排除密码框
-	allInputs, err := page.Elements("input")
-	if err == nil {
-		for _, input := range allInputs {
-			inputType, _ := input.Attribute("type")
-			if inputType != nil && *inputType == "password" {
-				continue // 跳过密码框
-			}
-			
-			if err := fillInputElement(input, config.Countindex, "用户名"); err == nil {
-				return nil
-			}
-		}
-	}
-	
-	return fmt.Errorf("找不到用户名输入框")
-}
-
 // waitForElementReady 等待元素准备好
 func waitForElementReady(element *rod.Element) error {
 	// 检查元素是否可见
@@ -353,236 +352,33 @@ func waitForElementReady(element *rod.Element) error {
 	return nil
 }
 
-// fillInputElement 填充输入框的通用函数
+// fillInputElement 填充输入框的通用函数，fieldName 出现在错误与日志中，用于区分是哪个输入框
 func fillInputElement(element *rod.Element, value string, fieldName string) error {
 	// 等待元素准备好
 	if err := waitForElementReady(element); err != nil {
-		return fmt.Errorf("%s输入框未准备好: %w", fieldName, err)
+		return fmt.Errorf("%s: 输入框未准备好: %w", fieldName, err)
 	}
 	
 	// 点击输入框
 	if err := element.Click(proto.InputMouseButtonLeft, 1); err != nil {
-		return fmt.Errorf("点击%s输入框失败: %w", fieldName, err)
+		return fmt.Errorf("%s: 点击输入框失败: %w", fieldName, err)
 	}
 	
 	// 全选并清空
 	if err := element.SelectAllText(); err != nil {
-		log.Printf("无法选择%s文本，尝试直接输入: %v", fieldName, err)
+		log.Printf("%s: 无法选择文本，尝试直接输入: %v", fieldName, err)
 		// 如果选择失败，尝试直接输入
 	}
 	
 	if err := element.Input(""); err != nil {
-		return fmt.Errorf("清空%s输入框失败: %w", fieldName, err)
+		return fmt.Errorf("%s: 清空输入框失败: %w", fieldName, err)
 	}
 	
 	// 输入值
 	if err := element.Input(value); err != nil {
-		return fmt.Errorf("输入%s失败: %w", fieldName, err)
+		return fmt.Errorf("%s: 输入失败: %w", fieldName, err)
 	}
 	
-	log.Printf("成功输入%s", fieldName)
+	log.Printf("%s: 输入成功", fieldName)
 	return nil
 }
-
-// inputPassword 输入密码
-func inputPassword(page *rod.Page, config *Config) error {
-	waiter := NewSmartWaiter(page)
-	
-	// 减少额外等待时间
-	time.Sleep(200 * time.Millisecond)
-	
-	// 定义多种可能的选择器
-	selectors := []ElementSelector{
-		{
-			Primary:      "input[type='password']",
-			Alternatives: []string{
-				"input[name='password']", 
-				"input[name='pwd_tip']", 
-				"input[id*='password']", 
-				"input[class*='password']",
-				"input[placeholder*='密码']",
-				"input[placeholder*='Password']",
-				"input[placeholder*='pass']",
-				"#password",
-				".password",
-			},
-			Attributes:   []string{"type", "name", "id", "class", "placeholder"},
-			TextContains: []string{"密码", "password", "pass"},
-		},
-	}
-	
-	// 先尝试查找所有密码输入框
-	elements, err := page.Elements("input[type='password']")
-	if err == nil && len(elements) > 0 {
-		log.Printf("找到 %d 个密码输入框，尝试第一个", len(elements))
-		element := elements[0]
-		return fillInputElement(element, config.Passwordindex, "密码")
-	}
-	
-	for _, selector := range selectors {
-		element, err := waiter.FindElementRobust(selector)
-		if err == nil {
-			return fillInputElement(element, config.Passwordindex, "密码")
-		}
-	}
-	
-	// 最后尝试：查找所有输入框，找到密码类型的
-	allInputs, err := page.Elements("input")
-	if err == nil {
-		for _, input := range allInputs {
-			inputType, _ := input.Attribute("type")
-			if inputType != nil && *inputType == "password" {
-				if err := fillInputElement(input, config.Passwordindex, "密码"); err == nil {
-					return nil
-				}
-			}
-		}
-	}
-	
-	return fmt.Errorf("找不到密码输入框")
-}
-
-// submitForm 提交表单
-func submitForm(page *rod.Page, config *Config) error {
-	waiter := NewSmartWaiter(page)
-	
-	// 尝试多种提交方式
-	submitWays := []func() error{
-		// 方式1: 按回车
-		func() error {
-			element, err := waiter.FindElementRobust(ElementSelector{
-				Primary:      "input[type='password']",
-				Alternatives: []string{"input[name='password']", "input[name='pwd_tip']"},
-			})
-			if err != nil {
-				return err
-			}
-			return element.Type(input.Enter)
-		},
-		// 方式2: 点击登录按钮
-		func() error {
-			selectors := []string{
-				"input[type='submit']",
-				"button[type='submit']",
-				"input[value*='登录']",
-				"button:contains('登录')",
-				"input[value*='Login']",
-				"button:contains('Login')",
-				"#logincompus_pc_hk_1",
-			}
-			
-			for _, selector := range selectors {
-				element, err := page.Element(selector)
-				if err == nil {
-					return element.Click(proto.InputMouseButtonLeft, 1)
-				}
-			}
-			return fmt.Errorf("找不到登录按钮")
-		},
-	}
-	
-	for _, submitFunc := range submitWays {
-		if err := submitFunc(); err == nil {
-			log.Printf("成功提交表单")
-			return nil
-		}
-	}
-	
-	return fmt.Errorf("无法提交表单")
-}
-
-// selectOperator 选择运营商
-func selectOperator(page *rod.Page, config *Config) error {
-	waiter := NewSmartWaiter(page)
-	
-	// 减少等待时间
-	time.Sleep(1 * time.Second)
-	
-	// 点击下拉菜单
-	selectors := []ElementSelector{
-		{
-			Primary:      "#selectDisname",
-			Alternatives: []string{"select[name='operator']", "select[id*='operator']", ".select-operator"},
-			Attributes:   []string{"id", "name", "class"},
-			TextContains: []string{"选择", "运营商", "请选择"},
-		},
-	}
-	
-	for _, selector := range selectors {
-		element, err := waiter.FindElementRobust(selector)
-		if err == nil {
-			if err := element.Click(proto.InputMouseButtonLeft, 1); err != nil {
-				continue
-			}
-			log.Printf("成功点击运营商选择框")
-			break
-		}
-	}
-	
-	// 减少等待下拉选项加载时间
-	time.Sleep(500 * time.Millisecond)
-	
-	// 选择具体的运营商
-	var serviceSelector string
-	switch config.Operatorindex {
-	case "a":
-		serviceSelector = "#_service_0"
-	case "b":
-		serviceSelector = "#_service_1"
-	case "c":
-		serviceSelector = "#_service_2"
-	case "d":
-		serviceSelector = "#_service_3"
-	default:
-		return fmt.Errorf("无效的运营商索引: %s", config.Operatorindex)
-	}
-	
-	// 尝试多种选择器
-	operatorSelectors := []string{
-		serviceSelector,
-		fmt.Sprintf("input[value='%s']", config.Operatorindex),
-		fmt.Sprintf("option[value='%s']", config.Operatorindex),
-	}
-	
-	for _, selector := range operatorSelectors {
-		element, err := page.Element(selector)
-		if err == nil {
-			if err := element.Click(proto.InputMouseButtonLeft, 1); err == nil {
-				log.Printf("成功选择运营商")
-				return nil
-			}
-		}
-	}
-	
-	return fmt.Errorf("无法选择运营商")
-}
-
-// confirmLogin 确认登录
-func confirmLogin(page *rod.Page, config *Config) error {
-	waiter := NewSmartWaiter(page)
-	
-	// 减少等待时间
-	time.Sleep(500 * time.Millisecond)
-	
-	// 查找最终登录按钮
-	selectors := []ElementSelector{
-		{
-			Primary:      "#loginLink",
-			Alternatives: []string{"input[type='submit']", "button[type='submit']", "button:contains('登录')", "a:contains('登录')", ".login-btn"},
-			Attributes:   []string{"id", "class", "type"},
-			TextContains: []string{"登录", "Login", "连接", "确认"},
-		},
-	}
-	
-	for _, selector := range selectors {
-		element, err := waiter.FindElementRobust(selector)
-		if err == nil {
-			if err := element.Click(proto.InputMouseButtonLeft, 1); err == nil {
-				log.Printf("成功点击登录确认按钮")
-				return nil
-			}
-		}
-	}
-	
-	return fmt.Errorf("无法找到登录确认按钮")
-}
\ No newline at end of file
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+)
+
+// LoginOutcome 登录结果分类
+type LoginOutcome string
+
+const (
+	// LoginOutcomeSuccess 认证成功，且已通过认证接口、成功页面或连通性检测确认
+	LoginOutcomeSuccess LoginOutcome = "success"
+	// LoginOutcomeFailed 认证失败
+	LoginOutcomeFailed LoginOutcome = "failed"
+	// LoginOutcomeUnverified 登录步骤已执行完毕，但无法确认是否认证成功
+	LoginOutcomeUnverified LoginOutcome = "unverified"
+)
+
+// loginVerifyTimeout 点击登录后等待认证结果的时间
+const loginVerifyTimeout = 8 * time.Second
+
+// ErrLoginUnverified 登录步骤执行完毕但无法确认认证结果
+var ErrLoginUnverified = errors.New("已提交登录，但未能确认认证结果")
+
+// ErrLogoutUnverified 已点击下线但无法确认是否注销成功
+var ErrLogoutUnverified = errors.New("已提交注销，但未能确认是否下线")
+
+// StepResult 单个登录步骤的执行情况
+type StepResult struct {
+	Name       string `json:"name"`
+	Attempts   int    `json:"attempts"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// LoginResult 一次登录的完整结果，供前端、命令行与日志区分"已登录"和"只是点了按钮"。
+// 注销同样使用该结构，Outcome 为 success 表示已下线
+type LoginResult struct {
+	Outcome    LoginOutcome `json:"outcome"`
+	Profile    string       `json:"profile"`
+	Operator   string       `json:"operator"`
+	Driver     string       `json:"driver"`
+	Steps      []StepResult `json:"steps"`
+	DurationMs int64        `json:"duration_ms"`
+	FinalURL   string       `json:"final_url"`
+	Message    string       `json:"message"`
+	IP         string       `json:"ip"`
+	Retries    int          `json:"retries"`
+	Error      string       `json:"error,omitempty"`
+	Reason     string       `json:"reason,omitempty"`
+	// Fallback 登录成功的步骤在配置的故障转移列表 (Config.Failover) 中从 1 开始的序号，
+	// 0 表示默认账号直接登录成功或登录失败
+	Fallback int            `json:"fallback"`
+	Attempts []LoginAttempt `json:"attempts,omitempty"`
+	// Recipe 浏览器登录使用的登录脚本
+	Recipe string `json:"recipe,omitempty"`
+}
+
+// newLoginResult 创建登录结果，IP 取自认证链接中网关分配的地址
+func newLoginResult(driver string, config *Config) *LoginResult {
+	return &LoginResult{
+		Outcome:  LoginOutcomeFailed,
+		Profile:  config.DefaultProfile,
+		Operator: config.Operatorindex,
+		Driver:   driver,
+		Steps:    []StepResult{},
+		IP:       assignedIP(config.Webindex),
+	}
+}
+
+// finish 记录总耗时与错误，返回原错误便于直接 return
+func (r *LoginResult) finish(start time.Time, err error) error {
+	r.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		r.Error = err.Error()
+		r.Reason = portalErrorCode(err)
+		if r.Outcome == LoginOutcomeSuccess {
+			r.Outcome = LoginOutcomeFailed
+		}
+	}
+	return err
+}
+
+// addStep 记录步骤执行情况，重试次数计入总数
+func (r *LoginResult) addStep(step StepResult) {
+	r.Steps = append(r.Steps, step)
+	if step.Attempts > 1 {
+		r.Retries += step.Attempts - 1
+	}
+}
+
+// runStep 执行只尝试一次的步骤（HTTP 驱动的各个请求），记录到结果中，
+// 与 ExecuteLoginStep 一样通过 ctx 中的监听器报告开始与结束
+func (r *LoginResult) runStep(ctx context.Context, name string, run func() error) error {
+	start := time.Now()
+	publishStep(ctx, StepEvent{Step: name, Status: StepStarted, Attempt: 1})
+
+	err := run()
+	step := StepResult{Name: name, Attempts: 1, DurationMs: time.Since(start).Milliseconds()}
+	event := StepEvent{Step: name, Status: StepSucceeded, Attempt: 1, ElapsedMs: step.DurationMs}
+	if err != nil {
+		step.Error = err.Error()
+		event.Status = StepFailed
+		event.Error = err.Error()
+	}
+	r.addStep(step)
+	publishStep(ctx, event)
+	return err
+}
+
+// assignedIP 从认证链接的 wlanuserip 参数中取出网关分配的地址
+func assignedIP(loginURL string) string {
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("wlanuserip")
+}
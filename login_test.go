@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/launcher"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestMain(m *testing.M) {
+	// 测试使用独立的配置目录与加密文件凭据存储，不影响本机配置与钥匙串
+	dir, err := os.MkdirTemp("", "yzuautologin-test")
+	if err != nil {
+		panic(err)
+	}
+	configDirFlag = dir
+	os.Setenv(secretBackendEnv, "file")
+	os.Setenv(secretPassphraseEnv, "test-passphrase")
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// saveTestConfig 写入指向模拟认证服务的配置
+func saveTestConfig(t *testing.T, portal *mockPortal, driver, password string) *Config {
+	t.Helper()
+
+	config := DefaultConfig()
+	config.Webindex = portal.LoginURL()
+	config.Countindex = "201900001"
+	config.Passwordindex = password
+	config.Operatorindex = "移动"
+	config.Driver = driver
+	if err := SaveConfig(config); err != nil {
+		t.Fatalf("保存配置失败: %v", err)
+	}
+	return config
+}
+
+// requireBrowser 本机没有可用的浏览器时跳过测试
+func requireBrowser(t *testing.T) string {
+	t.Helper()
+
+	info, err := ResolveBrowser("")
+	if err != nil {
+		t.Skip("未找到可用的浏览器，跳过浏览器模拟测试")
+	}
+	return info.Path
+}
+
+func TestHTTPDriverLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario mockScenario
+		password string
+		wantErr  error
+		wantMsg  string
+	}{
+		{name: "成功", scenario: scenarioSuccess, password: "correct-password"},
+		{name: "密码错误", scenario: scenarioWrongPassword, password: "wrong-password", wantErr: ErrBadCredentials, wantMsg: "密码错误"},
+		{name: "已在线", scenario: scenarioAlreadyOnline, password: "correct-password", wantErr: ErrAlreadyOnline, wantMsg: "已在线"},
+		{name: "欠费", scenario: scenarioSuspended, password: "correct-password", wantErr: ErrAccountSuspended, wantMsg: "欠费"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portal := newMockPortal(t, tt.scenario)
+			config := saveTestConfig(t, portal, "http", tt.password)
+
+			driver, err := NewLoginDriver(config.Driver)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := driver.Login(context.Background(), config)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("登录失败: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("期望错误 %v，实际为 %v", tt.wantErr, err)
+			}
+
+			wantOutcome := LoginOutcomeSuccess
+			if tt.wantErr != nil {
+				wantOutcome = LoginOutcomeFailed
+			}
+			if result.Outcome != wantOutcome {
+				t.Errorf("登录结果为 %s，期望 %s", result.Outcome, wantOutcome)
+			}
+			if result.IP != "10.20.30.40" {
+				t.Errorf("分配的IP为 %q", result.IP)
+			}
+			if tt.wantErr != nil && result.Reason != portalErrorCode(err) {
+				t.Errorf("错误代码为 %q", result.Reason)
+			}
+			if !strings.Contains(result.Message, tt.wantMsg) {
+				t.Errorf("认证页面提示为 %q", result.Message)
+			}
+
+			submissions := portal.Submissions()
+			if len(submissions) != 1 {
+				t.Fatalf("期望提交 1 次，实际 %d 次", len(submissions))
+			}
+			got := submissions[0]
+			if got.UserID != config.Countindex || got.Password != tt.password {
+				t.Errorf("提交的账号密码不正确: %+v", got)
+			}
+			if got.Service != "移动" {
+				t.Errorf("提交的运营商为 %q，期望 移动", got.Service)
+			}
+			if got.QueryString != mockQueryString {
+				t.Errorf("提交的 queryString 为 %q", got.QueryString)
+			}
+		})
+	}
+}
+
+func TestAutoDriverSkipsFallbackOnRejection(t *testing.T) {
+	portal := newMockPortal(t, scenarioWrongPassword)
+	saveTestConfig(t, portal, "auto", "wrong-password")
+
+	// 密码错误时不应回退到浏览器模拟，本机没有浏览器也能立即返回
+	result, err := NewApp().Loginyzu()
+	if !errors.Is(err, ErrBadCredentials) {
+		t.Fatalf("期望错误 %v，实际为 %v", ErrBadCredentials, err)
+	}
+	if result.Driver != "http" || result.Retries != 0 {
+		t.Errorf("登录结果不正确: %+v", result)
+	}
+	if len(portal.Submissions()) != 1 {
+		t.Errorf("期望提交 1 次，实际 %d 次", len(portal.Submissions()))
+	}
+}
+
+func TestHTTPDriverCanceled(t *testing.T) {
+	portal := newMockPortal(t, scenarioSlowLoad)
+	config := saveTestConfig(t, portal, "auto", "correct-password")
+
+	// 登录页面加载缓慢时取消，HTTP 请求立即中止，也不回退到浏览器模拟
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := (&autoDriver{primary: &httpDriver{}, fallback: &rodDriver{}}).Login(ctx, config)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("期望错误 %v，实际为 %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("取消后仍等待了 %v", elapsed)
+	}
+	if len(portal.Submissions()) != 0 {
+		t.Errorf("取消后不应提交认证")
+	}
+}
+
+func TestLoginyzuUsesConfiguredDriver(t *testing.T) {
+	portal := newMockPortal(t, scenarioSuccess)
+	saveTestConfig(t, portal, "http", "correct-password")
+
+	result, err := NewApp().Loginyzu()
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if result.Outcome != LoginOutcomeSuccess || result.Driver != "http" || len(result.Steps) != 1 {
+		t.Errorf("登录结果不正确: %+v", result)
+	}
+	if len(portal.Submissions()) != 1 {
+		t.Fatalf("期望提交 1 次，实际 %d 次", len(portal.Submissions()))
+	}
+}
+
+func TestConnectionSlowLoad(t *testing.T) {
+	portal := newMockPortal(t, scenarioSlowLoad)
+	saveTestConfig(t, portal, "http", "correct-password")
+
+	app := NewApp()
+	defer app.browsers.Close()
+
+	result, err := app.TestConnection()
+	if err != nil {
+		t.Fatalf("连接测试失败: %v", err)
+	}
+	if !strings.Contains(result, "成功") {
+		t.Errorf("连接测试结果不正确: %s", result)
+	}
+	if len(portal.Submissions()) != 0 {
+		t.Errorf("连接测试不应提交认证")
+	}
+}
+
+func TestRodLoginSteps(t *testing.T) {
+	bin := requireBrowser(t)
+
+	tests := []struct {
+		name        string
+		scenario    mockScenario
+		password    string
+		wantOutcome LoginOutcome
+		wantErr     error
+	}{
+		{name: "成功", scenario: scenarioSuccess, password: "correct-password", wantOutcome: LoginOutcomeSuccess},
+		{name: "密码错误", scenario: scenarioWrongPassword, password: "wrong-password", wantOutcome: LoginOutcomeFailed, wantErr: ErrBadCredentials},
+		{name: "加载缓慢", scenario: scenarioSlowLoad, password: "correct-password", wantOutcome: LoginOutcomeSuccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			portal := newMockPortal(t, tt.scenario)
+			config := saveTestConfig(t, portal, "rod", tt.password)
+
+			controlURL := launcher.New().Bin(bin).Headless(true).Set("no-proxy-server").MustLaunch()
+			browser := rod.New().ControlURL(controlURL).MustConnect()
+			defer browser.MustClose()
+
+			page, err := browser.Page(proto.TargetCreateTarget{URL: config.Webindex})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			inspector := NewPortalInspector(page)
+			defer inspector.Close()
+
+			recipe, err := selectRecipe(config, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, step := range recipe.LoginSteps() {
+				if _, err := ExecuteLoginStep(context.Background(), page, config, step); err != nil {
+					t.Fatalf("步骤 %s 失败: %v", step.Name, err)
+				}
+			}
+
+			result := newLoginResult("rod", config)
+			err = inspector.Verify(result)
+			if result.Outcome != tt.wantOutcome {
+				t.Errorf("登录结果为 %s，期望 %s (提示: %s)", result.Outcome, tt.wantOutcome, result.Message)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("期望错误 %v，实际为 %v", tt.wantErr, err)
+			}
+
+			submissions := portal.Submissions()
+			if len(submissions) == 0 {
+				t.Fatal("页面没有提交认证请求")
+			}
+			got := submissions[len(submissions)-1]
+			if got.UserID != config.Countindex || got.Password != tt.password || got.Service != "移动" {
+				t.Errorf("提交的数据不正确: %+v", got)
+			}
+		})
+	}
+}
+
+func TestRetryOperationContext(t *testing.T) {
+	// 单次尝试超时后继续重试
+	attempts := 0
+	err := RetryOperation(context.Background(), func() error {
+		attempts++
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	}, 3, time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) || attempts != 3 {
+		t.Errorf("单次尝试超时应继续重试: 尝试 %d 次，错误 %v", attempts, err)
+	}
+
+	// 操作取消后不再重试，也不等待重试间隔
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts = 0
+	start := time.Now()
+	err = RetryOperation(ctx, func() error {
+		attempts++
+		cancel()
+		return errors.New("页面卡住")
+	}, 3, time.Minute)
+	if !errors.Is(err, context.Canceled) || attempts != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("取消后应立即停止: 尝试 %d 次，错误 %v", attempts, err)
+	}
+}
+
+func TestConnectionWithRodDriver(t *testing.T) {
+	requireBrowser(t)
+
+	portal := newMockPortal(t, scenarioSuccess)
+	saveTestConfig(t, portal, "rod", "correct-password")
+
+	app := NewApp()
+	defer app.browsers.Close()
+
+	result, err := app.TestConnection()
+	if err != nil {
+		t.Fatalf("连接测试失败: %v", err)
+	}
+	if !strings.Contains(result, "用户名输入框: 找到") || !strings.Contains(result, "密码输入框: 找到") {
+		t.Errorf("连接测试结果不正确: %s", result)
+	}
+}
+
+func TestHTTPDriverLogout(t *testing.T) {
+	portal := newMockPortal(t, scenarioSuccess)
+	saveTestConfig(t, portal, "http", "correct-password")
+	rememberUserIndex("")
+
+	app := NewApp()
+	app.watchdog = NewWatchdog(time.Minute, nil)
+	if _, err := app.Logout(); !errors.Is(err, ErrNotOnline) {
+		t.Fatalf("未登录时注销期望 %v，实际为 %v", ErrNotOnline, err)
+	}
+	if app.watchdog.paused.Load() {
+		t.Error("注销失败时不应暂停网络守护")
+	}
+
+	if _, err := app.Loginyzu(); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+
+	result, err := app.Logout()
+	if err != nil {
+		t.Fatalf("注销失败: %v", err)
+	}
+	if result.Outcome != LoginOutcomeSuccess || result.Driver != "http" || len(result.Steps) != 2 {
+		t.Errorf("注销结果不正确: %+v", result)
+	}
+	if !strings.Contains(result.Message, "下线成功") {
+		t.Errorf("认证页面提示为 %q", result.Message)
+	}
+	if portal.Logouts() != 1 {
+		t.Errorf("期望注销 1 次，实际 %d 次", portal.Logouts())
+	}
+	if !app.watchdog.paused.Load() {
+		t.Error("注销成功后应暂停网络守护")
+	}
+}
+
+func TestRodLogout(t *testing.T) {
+	requireBrowser(t)
+
+	portal := newMockPortal(t, scenarioSuccess)
+	config := saveTestConfig(t, portal, "http", "correct-password")
+	if _, err := loginWithHTTP(context.Background(), config); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+
+	service := NewBrowserService(time.Minute)
+	defer service.Close()
+
+	result, err := logoutWithRod(WithBrowserService(context.Background(), service), config)
+	if err != nil {
+		t.Fatalf("注销失败: %v (步骤: %+v)", err, result.Steps)
+	}
+	if result.Outcome != LoginOutcomeSuccess || !strings.Contains(result.Message, "下线成功") {
+		t.Errorf("注销结果不正确: %+v", result)
+	}
+	if portal.Logouts() != 1 {
+		t.Errorf("期望注销 1 次，实际 %d 次", portal.Logouts())
+	}
+}
+
+func TestGetSessionInfo(t *testing.T) {
+	portal := newMockPortal(t, scenarioSuccess)
+	saveTestConfig(t, portal, "http", "correct-password")
+	rememberUserIndex("")
+
+	app := NewApp()
+	info, err := app.GetSessionInfo()
+	if err != nil {
+		t.Fatalf("查询在线信息失败: %v", err)
+	}
+	if info.Online {
+		t.Fatalf("未登录时不应在线: %+v", info)
+	}
+
+	if _, err := app.Loginyzu(); err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+
+	info, err = app.GetSessionInfo()
+	if err != nil {
+		t.Fatalf("查询在线信息失败: %v", err)
+	}
+	if !info.Online || info.UserID != "201900001" || info.IP != "10.20.30.40" || info.Service != "移动" {
+		t.Errorf("在线信息不正确: %+v", info)
+	}
+	if info.UsedTraffic != "1.25GB" || info.OnlineTime != "2小时15分" || info.Balance != "18.50元" {
+		t.Errorf("流量、时长或余额不正确: %+v", info)
+	}
+	if len(info.Details) != 3 {
+		t.Errorf("附加信息为 %+v", info.Details)
+	}
+}
+
+func TestListOperators(t *testing.T) {
+	portal := newMockPortal(t, scenarioSuccess)
+	saveTestConfig(t, portal, "http", "correct-password")
+
+	operators, err := NewApp().ListOperators()
+	if err != nil {
+		t.Fatalf("读取运营商列表失败: %v", err)
+	}
+	if len(operators) != 4 || operators[3].ID != "_service_3" || operators[3].Value != "电信" {
+		t.Errorf("运营商列表不正确: %+v", operators)
+	}
+}
+
+func TestMatchOperator(t *testing.T) {
+	// 服务顺序与扬州大学不同，且使用下拉框的认证页面
+	page := `<html><body><select name="service">
+		<option value="">请选择</option>
+		<option value="CMCC">中国移动(100M)</option>
+		<option value="campus">校园网</option>
+		<option value="CTCC">中国电信</option>
+	</select></body></html>`
+	operators, err := parseOperators(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(operators) != 3 {
+		t.Fatalf("解析的服务为 %+v", operators)
+	}
+
+	tests := []struct {
+		name      string
+		wantValue string
+		wantOK    bool
+	}{
+		{"移动", "CMCC", true},
+		{"mobile", "CMCC", true},
+		{"telecom", "CTCC", true},
+		{"校园网", "campus", true},
+		{"联通", "", false},
+	}
+	for _, tt := range tests {
+		operator, ok := matchOperator(operators, tt.name)
+		if ok != tt.wantOK || operator.Value != tt.wantValue {
+			t.Errorf("matchOperator(%q) = %+v, %v，期望值 %q", tt.name, operator, ok, tt.wantValue)
+		}
+	}
+}
+
+func TestNameOperators(t *testing.T) {
+	operators := nameOperators([]Operator{
+		{Label: "中国移动(100M)", Value: "CMCC-100"},
+		{Label: "中国移动(50M)", Value: "CMCC-50"},
+		{Label: "校园网", Value: "campus"},
+		{Label: "教师专线", Value: "teacher"},
+	})
+
+	// 界面保存的名称经 matchOperator 匹配回同一个服务
+	want := []string{"移动", "中国移动(50M)", "校园网", "教师专线"}
+	for i, operator := range operators {
+		if operator.Name != want[i] {
+			t.Errorf("服务 %s 的名称为 %q，期望 %q", operator.Label, operator.Name, want[i])
+		}
+		if matched, ok := matchOperator(operators, operator.Name); !ok || matched.Value != operator.Value {
+			t.Errorf("名称 %q 匹配到 %+v", operator.Name, matched)
+		}
+	}
+
+	// 标签与另一个服务的标准名称相同时，名称仍然唯一且匹配回各自的服务
+	operators = nameOperators([]Operator{
+		{Label: "中国移动(100M)", Value: "CMCC-100"},
+		{Label: "移动", Value: "CMCC"},
+	})
+	want = []string{"中国移动(100M)", "移动"}
+	for i, operator := range operators {
+		if operator.Name != want[i] {
+			t.Errorf("服务 %s 的名称为 %q，期望 %q", operator.Label, operator.Name, want[i])
+		}
+		if matched, ok := matchOperator(operators, operator.Name); !ok || matched.Value != operator.Value {
+			t.Errorf("名称 %q 匹配到 %+v", operator.Name, matched)
+		}
+	}
+
+	if got := normalizeOperator(" telecom "); got != "电信" {
+		t.Errorf("别名没有转换为标准名称: %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// Logout 注销当前在线的认证用户，结果与登录使用相同的结构
+func (a *App) Logout() (*LoginResult, error) {
+	// 先登记操作，等待其他登录时也能在操作列表中看到并取消
+	ctx, done := a.track(OperationLogout)
+	defer done()
+
+	release, err := a.acquireLogin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	log.Println("开始执行注销流程")
+
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error reading config: %w", err)
+	}
+
+	driver, err := NewLoginDriver(config.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := driver.Logout(ctx, config)
+	if err != nil {
+		return result, err
+	}
+
+	// 主动下线成功后暂停网络守护，避免马上又被自动登录；注销失败时保持自动重新登录
+	if a.watchdog != nil {
+		a.watchdog.Pause()
+	}
+
+	log.Printf("注销流程执行完成: %s (驱动: %s, 耗时: %dms)", result.Outcome, result.Driver, result.DurationMs)
+	return result, nil
+}
+
+// logoutWithRod 打开认证页面，在线时会跳转到成功页面，点击其中的下线按钮
+func logoutWithRod(ctx context.Context, config *Config) (*LoginResult, error) {
+	start := time.Now()
+	result := newLoginResult("rod", config)
+
+	page, cleanup, err := openBrowserPage(ctx, config.Webindex, true)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+	defer cleanup()
+
+	// 下线前通常会弹出确认框，由检查器自动确认
+	inspector := NewPortalInspector(page)
+	defer inspector.Close()
+
+	for _, step := range GetLogoutSteps() {
+		stepResult, err := ExecuteLoginStep(ctx, page, config, step)
+		result.addStep(stepResult)
+		if err != nil {
+			return result, result.finish(start, fmt.Errorf("注销流程在 '%s' 步骤失败: %w", step.Name, err))
+		}
+	}
+
+	log.Println("等待注销完成...")
+	err = inspector.VerifyLogout(result)
+	if err == nil {
+		rememberUserIndex("")
+	}
+	return result, result.finish(start, err)
+}
+
+// GetLogoutSteps 获取注销步骤序列
+func GetLogoutSteps() []LoginStep {
+	return []LoginStep{
+		{
+			Name:        "等待页面加载",
+			Description: "等待认证页面完全加载",
+			Execute:     waitForPageLoad,
+			MaxRetries:  2,
+			Timeout:     8 * time.Second,
+		},
+		{
+			Name:        "点击下线",
+			Description: "点击成功页面上的下线按钮",
+			Execute:     clickLogout,
+			MaxRetries:  2,
+			Timeout:     3 * time.Second,
+		},
+	}
+}
+
+// clickLogout 点击下线按钮，页面仍是登录表单时说明当前没有在线用户
+func clickLogout(ctx context.Context, page *rod.Page, config *Config) error {
+	waiter := NewSmartWaiter(ctx, page)
+
+	element, err := waiter.FindElementRobust(ElementSelector{
+		Primary:      "#toLogOut",
+		Alternatives: []string{"#logout", ".logout", "a[onclick*='logout']", "input[value='下线']"},
+	})
+	if err != nil {
+		if has, _, _ := page.Has("input[type='password']"); has {
+			return ErrNotOnline
+		}
+		return fmt.Errorf("无法找到下线按钮: %w", err)
+	}
+
+	if err := element.Click(proto.InputMouseButtonLeft, 1); err != nil {
+		return fmt.Errorf("点击下线按钮失败: %w", err)
+	}
+
+	log.Printf("成功点击下线按钮")
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"os"
 
     "github.com/wailsapp/wails/v2"
     "github.com/wailsapp/wails/v2/pkg/options"
@@ -22,6 +23,14 @@ var assets embed.FS
 
 
 func main() {
+	// --config-dir 与 --portable 对界面和命令行模式都生效
+	args := applyGlobalFlags(os.Args[1:])
+
+	// 带子命令运行时进入命令行模式，不启动界面
+	if isCLICommand(args) {
+		os.Exit(runCLI(args))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 	// go systray.Run(func() { onReady(app) }, onExit)
@@ -36,6 +45,8 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 0, G: 0, B: 0, A: 0},
 		OnStartup:        app.startup,
+		OnShutdown:       app.shutdown,
+		ErrorFormatter:   formatBindingError,
         Frameless: true,
         DisableResize: true,
 		Bind: []interface{}{
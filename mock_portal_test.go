@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockScenario 模拟认证页面的不同响应
+type mockScenario int
+
+const (
+	scenarioSuccess       mockScenario = iota // 认证成功
+	scenarioWrongPassword                     // 密码错误
+	scenarioAlreadyOnline                     // 账号已在线
+	scenarioSlowLoad                          // 登录页面加载缓慢
+	scenarioSuspended                         // 账号欠费停机
+	scenarioOperatorDown                      // 移动服务不可用，其他运营商正常
+)
+
+// mockSubmission 认证接口收到的一次提交
+type mockSubmission struct {
+	UserID      string
+	Password    string
+	Service     string
+	QueryString string
+}
+
+// mockPortal 基于 httptest 的 ePortal 模拟服务，页面结构与扬州大学认证页面一致
+type mockPortal struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	scenario    mockScenario
+	password    string
+	loadDelay   time.Duration
+	submissions []mockSubmission
+	online      bool
+	logouts     int
+}
+
+// mockUserIndex 模拟认证成功后分配的 userIndex
+const mockUserIndex = "mock-user-index"
+
+// mockBallInfo 在线用户信息中以字符串形式嵌套的附加信息
+const mockBallInfo = `[{"id":"flow","displayName":"已用流量","value":"1.25GB"},` +
+	`{"id":"onlineTime","displayName":"在线时长","value":"2小时15分"},` +
+	`{"id":"balance","displayName":"账户余额","value":"18.50元"}]`
+
+// mockQueryString 模拟认证网关重定向时附带的查询参数
+const mockQueryString = "wlanuserip=10.20.30.40&wlanacname=YZU-AC&nasip=10.0.0.2&mac=aabbccddeeff"
+
+// newMockPortal 启动模拟认证服务，测试结束时自动关闭
+func newMockPortal(t *testing.T, scenario mockScenario) *mockPortal {
+	t.Helper()
+
+	portal := &mockPortal{
+		scenario:  scenario,
+		password:  "correct-password",
+		loadDelay: 2 * time.Second,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/eportal/index.jsp", portal.handleIndex)
+	mux.HandleFunc("/eportal/InterFace.do", portal.handleInterface)
+	mux.HandleFunc("/eportal/success.jsp", portal.handleSuccess)
+
+	portal.Server = httptest.NewServer(mux)
+	t.Cleanup(portal.Close)
+	return portal
+}
+
+// LoginURL 返回与真实网关重定向格式一致的登录链接
+func (p *mockPortal) LoginURL() string {
+	return p.URL + "/eportal/index.jsp?" + mockQueryString
+}
+
+// Submissions 返回收到的所有认证提交
+func (p *mockPortal) Submissions() []mockSubmission {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]mockSubmission(nil), p.submissions...)
+}
+
+// Logouts 返回收到的注销请求次数
+func (p *mockPortal) Logouts() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.logouts
+}
+
+func (p *mockPortal) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if p.scenario == scenarioSlowLoad {
+		time.Sleep(p.loadDelay)
+	}
+
+	// 与真实网关一致，已在线时访问认证页面会跳转到成功页面
+	p.mu.Lock()
+	online := p.online
+	p.mu.Unlock()
+	if online {
+		http.Redirect(w, r, "success.jsp?userIndex="+mockUserIndex, http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, mockIndexPage)
+}
+
+func (p *mockPortal) handleInterface(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	switch r.URL.Query().Get("method") {
+	case "login":
+		submission := mockSubmission{
+			UserID:      r.PostForm.Get("userId"),
+			Password:    r.PostForm.Get("password"),
+			Service:     r.PostForm.Get("service"),
+			QueryString: r.PostForm.Get("queryString"),
+		}
+		response := p.loginResponse(submission)
+		p.mu.Lock()
+		p.submissions = append(p.submissions, submission)
+		if response.Result == "success" {
+			p.online = true
+		}
+		p.mu.Unlock()
+
+		json.NewEncoder(w).Encode(response)
+	case "logout":
+		p.mu.Lock()
+		p.logouts++
+		ok := p.online && r.PostForm.Get("userIndex") == mockUserIndex
+		if ok {
+			p.online = false
+		}
+		p.mu.Unlock()
+
+		if !ok {
+			json.NewEncoder(w).Encode(ePortalResponse{Result: "fail", Message: "用户已不在线"})
+			return
+		}
+		json.NewEncoder(w).Encode(ePortalResponse{Result: "success", Message: "下线成功！"})
+	case "getOnlineUserInfo":
+		p.mu.Lock()
+		online := p.online
+		p.mu.Unlock()
+
+		if !online {
+			json.NewEncoder(w).Encode(ePortalResponse{Result: "fail", Message: "用户不在线"})
+			return
+		}
+		json.NewEncoder(w).Encode(ePortalUserInfo{
+			ePortalResponse: ePortalResponse{Result: "success", UserIndex: mockUserIndex},
+			UserName:        "测试用户",
+			UserID:          "201900001",
+			UserIP:          "10.20.30.40",
+			UserMAC:         "aa:bb:cc:dd:ee:ff",
+			Service:         "移动",
+			BallInfo:        mockBallInfo,
+		})
+	default:
+		http.Error(w, "unknown method", http.StatusNotFound)
+	}
+}
+
+// loginResponse 根据场景生成认证接口的返回结果
+func (p *mockPortal) loginResponse(submission mockSubmission) ePortalResponse {
+	switch {
+	case p.scenario == scenarioWrongPassword || submission.Password != p.password:
+		return ePortalResponse{Result: "fail", Message: "用户名或密码错误"}
+	case p.scenario == scenarioAlreadyOnline:
+		return ePortalResponse{Result: "fail", Message: "该账号已在线，请勿重复认证"}
+	case p.scenario == scenarioOperatorDown && submission.Service == "移动":
+		return ePortalResponse{Result: "fail", Message: "所选服务不可用，请选择其他服务"}
+	case p.scenario == scenarioSuspended:
+		return ePortalResponse{Result: "fail", Message: "您的账户已欠费，请充值后再试"}
+	}
+	return ePortalResponse{Result: "success", UserIndex: mockUserIndex}
+}
+
+func (p *mockPortal) handleSuccess(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, mockSuccessPage)
+}
+
+// mockSuccessPage 模拟认证成功页面，下线前弹出确认框，下线后返回认证页面
+const mockSuccessPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>登录成功</title></head>
+<body>
+<div id="userMessage">您已成功连接校园网</div>
+<a id="toLogOut" href="javascript:void(0)">下线</a>
+<script>
+document.getElementById('toLogOut').addEventListener('click', function () {
+	if (!confirm('确定要下线吗？')) {
+		return;
+	}
+	var body = new URLSearchParams();
+	body.set('userIndex', new URLSearchParams(window.location.search).get('userIndex'));
+	fetch('InterFace.do?method=logout', {method: 'POST', body: body})
+		.then(function (resp) { return resp.json(); })
+		.then(function (result) {
+			alert(result.message);
+			window.location.href = 'index.jsp?' + '` + mockQueryString + `';
+		});
+});
+</script>
+</body>
+</html>`
+
+// mockIndexPage 模拟认证页面，包含登录步骤依赖的全部元素
+const mockIndexPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>扬州大学校园网认证</title>
+</head>
+<body>
+<form id="loginForm" onsubmit="return false;">
+	<input type="text" name="username_tip" id="username" placeholder="学号/工号">
+	<input type="password" name="pwd_tip" id="pwd" placeholder="密码">
+	<div id="selectDisname">请选择服务</div>
+	<ul id="serviceList" style="display:none">
+		<li id="_service_0" data-service="校园网">校园网</li>
+		<li id="_service_1" data-service="联通">联通</li>
+		<li id="_service_2" data-service="移动">移动</li>
+		<li id="_service_3" data-service="电信">电信</li>
+	</ul>
+	<input type="hidden" id="service" value="">
+	<a id="loginLink" href="javascript:void(0)">登录</a>
+	<div id="errorInfo_center"></div>
+</form>
+<script>
+document.getElementById('selectDisname').addEventListener('click', function () {
+	document.getElementById('serviceList').style.display = 'block';
+});
+document.querySelectorAll('#serviceList li').forEach(function (item) {
+	item.addEventListener('click', function () {
+		document.getElementById('service').value = item.dataset.service;
+		document.getElementById('selectDisname').textContent = item.textContent;
+		document.getElementById('serviceList').style.display = 'none';
+	});
+});
+document.getElementById('loginLink').addEventListener('click', function () {
+	var body = new URLSearchParams();
+	body.set('userId', document.getElementById('username').value);
+	body.set('password', document.getElementById('pwd').value);
+	body.set('service', document.getElementById('service').value);
+	body.set('queryString', window.location.search.substring(1));
+	fetch('InterFace.do?method=login', {method: 'POST', body: body})
+		.then(function (resp) { return resp.json(); })
+		.then(function (result) {
+			if (result.result === 'success') {
+				window.location.href = 'success.jsp?userIndex=' + result.userIndex;
+			} else {
+				document.getElementById('errorInfo_center').textContent = result.message;
+			}
+		});
+});
+</script>
+</body>
+</html>`
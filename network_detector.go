@@ -3,52 +3,53 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
 	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/proto"
 )
 
 // NetworkDetector 校园网检测器
 type NetworkDetector struct {
+	lease   *BrowserLease
 	browser *rod.Browser
 	timeout time.Duration
 }
 
-// NewNetworkDetector 创建新的网络检测器
-func NewNetworkDetector(timeout time.Duration) (*NetworkDetector, error) {
-	launcher := launcher.New().Headless(true).Set("no-proxy-server")
-	controlURL, err := launcher.Launch()
+// NewNetworkDetector 创建新的网络检测器，从 ctx 中的浏览器服务租用无界面浏览器，ctx 结束时自动归还浏览器。
+// timeout 为访问单个地址的超时
+func NewNetworkDetector(ctx context.Context, timeout time.Duration) (*NetworkDetector, error) {
+	service, err := browserServiceFrom(ctx)
 	if err != nil {
-		launcher.Kill()
-		return nil, fmt.Errorf("浏览器启动失败: %w", err)
+		return nil, err
 	}
-
-	browser := rod.New().ControlURL(controlURL)
-	if err := browser.Connect(); err != nil {
-		launcher.Kill()
-		return nil, fmt.Errorf("浏览器连接失败: %w", err)
+	lease, err := service.Acquire(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("浏览器启动失败: %w", err)
 	}
 
 	return &NetworkDetector{
-		browser: browser,
+		lease:   lease,
+		browser: lease.Browser(),
 		timeout: timeout,
 	}, nil
 }
 
-// Close 关闭检测器资源
+// Close 归还浏览器，浏览器由浏览器服务统一关闭
 func (nd *NetworkDetector) Close() {
-	if nd.browser != nil {
-		nd.browser.Close()
+	if nd.lease != nil {
+		nd.lease.Release()
 	}
 }
 
-// DetectLoginPage 检测校园网登录页面
-func (nd *NetworkDetector) DetectLoginPage() (string, error) {
+// DetectLoginPage 检测校园网登录页面，ctx 结束时停止检测
+func (nd *NetworkDetector) DetectLoginPage(ctx context.Context) (string, error) {
 	log.Println("开始检测校园网登录页面...")
 
 	// 尝试多个可能的入口点
@@ -69,16 +70,19 @@ func (nd *NetworkDetector) DetectLoginPage() (string, error) {
 	var lastErr error
 
 	for _, testURL := range testURLs {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		log.Printf("尝试访问: %s", testURL)
 		
-		url, err := nd.tryAccessURL(testURL)
+		url, body, err := nd.tryAccessURL(ctx, testURL)
 		if err != nil {
 			lastErr = err
 			continue
 		}
 
 		// 检查是否是登录页面
-		if nd.isLoginPage(url) {
+		if nd.isLoginPage(url, body) {
 			loginURL = url
 			log.Printf("找到登录页面: %s", loginURL)
 			break
@@ -92,47 +96,51 @@ func (nd *NetworkDetector) DetectLoginPage() (string, error) {
 	return loginURL, nil
 }
 
-// tryAccessURL 尝试访问URL并跟踪重定向
-func (nd *NetworkDetector) tryAccessURL(initialURL string) (string, error) {
-	page, err := nd.browser.Page(proto.TargetCreateTarget{URL: initialURL})
+// tryAccessURL 尝试访问URL并跟踪重定向，返回最终URL与页面源码
+func (nd *NetworkDetector) tryAccessURL(ctx context.Context, initialURL string) (string, string, error) {
+	// 页面绑定 ctx，取消时页面上的操作随之中止
+	page, err := nd.browser.Context(ctx).Page(proto.TargetCreateTarget{URL: initialURL})
 	if err != nil {
-		return "", fmt.Errorf("创建页面失败: %w", err)
+		return "", "", fmt.Errorf("创建页面失败: %w", err)
 	}
-	defer page.Close()
-
-	// 设置超时上下文
-	_, cancel := context.WithTimeout(context.Background(), nd.timeout)
-	defer cancel()
-
-	// 监听网络响应
-	router := page.HijackRequests()
-	router.MustAdd("*", func(ctx *rod.Hijack) {
-		ctx.ContinueRequest(&proto.FetchContinueRequest{})
-	})
-	go router.Run()
+	// ctx 可能已经结束，关闭页面不能再使用它
+	defer page.Context(context.Background()).Close()
 
 	// 等待页面加载
-	err = rod.Try(func() {
-		page.Timeout(nd.timeout).WaitLoad()
-	})
-	if err != nil {
-		return "", fmt.Errorf("页面加载超时: %w", err)
+	if err := page.Timeout(nd.timeout).WaitLoad(); err != nil {
+		return "", "", fmt.Errorf("页面加载失败: %w", err)
 	}
 
 	// 获取最终URL
 	finalURL, err := page.Eval(`() => window.location.href`)
 	if err != nil {
-		return "", fmt.Errorf("获取页面URL失败: %w", err)
+		return "", "", fmt.Errorf("获取页面URL失败: %w", err)
 	}
 
 	finalURLStr := finalURL.Value.String()
 	log.Printf("初始URL: %s -> 最终URL: %s", initialURL, finalURLStr)
+
+	body, err := page.HTML()
+	if err != nil {
+		log.Printf("读取页面源码失败: %v", err)
+	}
 	
-	return finalURLStr, nil
+	return finalURLStr, body, nil
 }
 
-// isLoginPage 判断页面是否为登录页面
-func (nd *NetworkDetector) isLoginPage(urlStr string) bool {
+// isLoginPage 判断页面是否为登录页面：能识别出认证系统的页面一定是登录页面，否则按URL特征判断
+func (nd *NetworkDetector) isLoginPage(urlStr, body string) bool {
+	if body != "" {
+		if fingerprint := fingerprintPortal(urlStr, []byte(body)); fingerprint.Vendor != VendorUnknown {
+			log.Printf("识别到认证系统: %s %s", fingerprint.Name, fingerprint.Version)
+			return true
+		}
+	}
+	return isLoginPageURL(urlStr)
+}
+
+// isLoginPageURL 根据URL特征判断是否为登录页面
+func isLoginPageURL(urlStr string) bool {
 	// 检查URL特征
 	u, err := url.Parse(urlStr)
 	if err != nil {
@@ -169,65 +177,58 @@ func (nd *NetworkDetector) isLoginPage(urlStr string) bool {
 }
 
 // TestNetworkConnectivity 测试网络连通性
-func (nd *NetworkDetector) TestNetworkConnectivity() (bool, string, error) {
+func (nd *NetworkDetector) TestNetworkConnectivity(ctx context.Context) (bool, string, error) {
 	log.Println("测试网络连通性...")
 
 	// 尝试访问一个稳定的网站
 	testURL := "http://www.baidu.com"
 	
-	page, err := nd.browser.Page(proto.TargetCreateTarget{URL: testURL})
+	page, err := nd.browser.Context(ctx).Page(proto.TargetCreateTarget{URL: testURL})
 	if err != nil {
 		return false, "", fmt.Errorf("创建测试页面失败: %w", err)
 	}
-	defer page.Close()
+	defer page.Context(context.Background()).Close()
 
-	// 设置超时
-	_, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	if err := page.Timeout(10 * time.Second).WaitLoad(); err != nil {
+		return false, "", fmt.Errorf("网络测试失败: %w", err)
+	}
 
-	var result string
-	var isConnected bool
+	// 检查页面标题或内容
+	title, err := page.Eval(`() => document.title`)
+	if err != nil {
+		return false, "", fmt.Errorf("获取页面标题失败: %w", err)
+	}
+	location, err := page.Eval(`() => window.location.href`)
+	if err != nil {
+		return false, "", fmt.Errorf("获取页面URL失败: %w", err)
+	}
 
-	err = rod.Try(func() {
-		page.Timeout(10 * time.Second).WaitLoad()
-		
-		// 检查页面标题或内容
-		title, _ := page.Eval(`() => document.title`)
-		location, _ := page.Eval(`() => window.location.href`)
-		
-		finalURL := location.Value.String()
-		pageTitle := title.Value.String()
-		
-		if strings.Contains(finalURL, "baidu.com") || strings.Contains(pageTitle, "百度") {
-			isConnected = true
-			result = "网络已连接，可以正常访问互联网"
-		} else {
-			// 检查是否被重定向到登录页面
-			if nd.isLoginPage(finalURL) {
-				isConnected = false
-				result = fmt.Sprintf("网络需要认证，已重定向到登录页面: %s", finalURL)
-			} else {
-				isConnected = false
-				result = fmt.Sprintf("网络状态未知，最终URL: %s", finalURL)
-			}
-		}
-	})
+	finalURL := location.Value.String()
+	pageTitle := title.Value.String()
 
-	if err != nil {
-		return false, "", fmt.Errorf("网络测试失败: %w", err)
+	if strings.Contains(finalURL, "baidu.com") || strings.Contains(pageTitle, "百度") {
+		return true, "网络已连接，可以正常访问互联网", nil
 	}
 
-	return isConnected, result, nil
+	// 检查是否被重定向到登录页面
+	body, err := page.HTML()
+	if err != nil {
+		return false, "", fmt.Errorf("读取页面源码失败: %w", err)
+	}
+	if nd.isLoginPage(finalURL, body) {
+		return false, fmt.Sprintf("网络需要认证，已重定向到登录页面: %s", finalURL), nil
+	}
+	return false, fmt.Sprintf("网络状态未知，最终URL: %s", finalURL), nil
 }
 
 // GetNetworkStatus 获取网络状态信息
-func (nd *NetworkDetector) GetNetworkStatus() (map[string]interface{}, error) {
+func (nd *NetworkDetector) GetNetworkStatus(ctx context.Context) (map[string]interface{}, error) {
 	log.Println("获取网络状态信息...")
 
 	status := make(map[string]interface{})
 	
 	// 测试连通性
-	connected, connResult, err := nd.TestNetworkConnectivity()
+	connected, connResult, err := nd.TestNetworkConnectivity(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -237,7 +238,7 @@ func (nd *NetworkDetector) GetNetworkStatus() (map[string]interface{}, error) {
 
 	// 如果未连接，尝试检测登录页面
 	if !connected {
-		loginURL, err := nd.DetectLoginPage()
+		loginURL, err := nd.DetectLoginPage(ctx)
 		if err == nil {
 			status["login_url"] = loginURL
 			status["needs_authentication"] = true
@@ -250,4 +251,62 @@ func (nd *NetworkDetector) GetNetworkStatus() (map[string]interface{}, error) {
 	}
 
 	return status, nil
-}
\ No newline at end of file
+}
+
+// portalRedirectPattern 匹配认证网关注入的脚本跳转
+var portalRedirectPattern = regexp.MustCompile(`location\.href\s*=\s*['"]([^'"]+)['"]`)
+
+// 连通性检测请求的页面，以及网络正常时页面中一定包含的内容
+var (
+	connectivityProbeURL     = "http://www.baidu.com"
+	connectivityProbeKeyword = "百度"
+)
+
+// probeTransport 连通性检测使用的连接，不走代理。
+// 认证前被网关劫持的连接在认证后不能继续使用，因此不保留空闲连接
+var probeTransport = &http.Transport{
+	Proxy:             nil,
+	DisableKeepAlives: true,
+}
+
+// CheckConnectivity 不启动浏览器，通过 HTTP 请求快速检测网络连通性。
+// 未连接且被劫持到认证页面时，返回认证页面地址。
+func CheckConnectivity(ctx context.Context, timeout time.Duration) (bool, string, error) {
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: probeTransport,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, connectivityProbeURL, nil)
+	if err != nil {
+		return false, "", fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, "", fmt.Errorf("网络测试失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return false, "", fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	finalURL := resp.Request.URL.String()
+	if isLoginPageURL(finalURL) {
+		return false, finalURL, nil
+	}
+
+	// 先确认是否拿到了真正的检测页面：页面自身的脚本中也可能有指向 login 地址的跳转
+	if resp.StatusCode == http.StatusOK && resp.Request.URL.Host == req.URL.Host &&
+		strings.Contains(string(body), connectivityProbeKeyword) {
+		return true, "", nil
+	}
+
+	// 认证网关通常返回一段脚本跳转到登录页
+	if match := portalRedirectPattern.FindSubmatch(body); match != nil && isLoginPageURL(string(match[1])) {
+		return false, string(match[1]), nil
+	}
+
+	return false, "", nil
+}
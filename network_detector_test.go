@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// setConnectivityProbe 让连通性检测请求测试服务器
+func setConnectivityProbe(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	original := connectivityProbeURL
+	connectivityProbeURL = server.URL
+	t.Cleanup(func() { connectivityProbeURL = original })
+}
+
+// gatewayRedirectPage 认证网关劫持请求后返回的跳转脚本
+const gatewayRedirectPage = `<script>top.self.location.href='http://10.0.0.1/eportal/index.jsp?wlanuserip=10.0.0.2'</script>`
+
+func TestCheckConnectivity(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		connected bool
+		portalURL string
+	}{
+		{"网络正常", http.StatusOK, "<title>百度一下，你就知道</title>", true, ""},
+		{"页面脚本中有登录跳转", http.StatusOK,
+			`<title>百度一下</title><script>if(!user){location.href="https://passport.baidu.com/v2/?login"}</script>`, true, ""},
+		{"被劫持到认证页面", http.StatusOK, gatewayRedirectPage, false, "http://10.0.0.1/eportal/index.jsp?wlanuserip=10.0.0.2"},
+		{"网关返回错误", http.StatusBadGateway, "百度", false, ""},
+		{"未知页面", http.StatusOK, "<html></html>", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConnectivityProbe(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			connected, portalURL, err := CheckConnectivity(context.Background(), 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if connected != tt.connected || portalURL != tt.portalURL {
+				t.Errorf("期望 %v %q，实际为 %v %q", tt.connected, tt.portalURL, connected, portalURL)
+			}
+		})
+	}
+}
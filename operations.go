@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 可取消的长时间操作类型
+const (
+	OperationLogin     = "login"
+	OperationLogout    = "logout"
+	OperationProbe     = "probe"
+	OperationDetect    = "detect"
+	OperationStatus    = "status"
+	OperationSession   = "session"
+	OperationOperators = "operators"
+)
+
+// operationFinishedEvent StartLogin 等异步操作结束时发送给前端的事件
+const operationFinishedEvent = "operation:finished"
+
+// ErrOperationNotFound 要取消的操作不存在或已经结束
+var ErrOperationNotFound = errors.New("操作不存在或已经结束")
+
+// OperationInfo 进行中的操作
+type OperationInfo struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// StartedAt 开始时间，Unix 毫秒
+	StartedAt int64 `json:"started_at"`
+}
+
+// OperationEvent 异步操作结束时发送的事件，Error 与绑定方法返回的错误格式相同
+type OperationEvent struct {
+	ID       string       `json:"id"`
+	Kind     string       `json:"kind"`
+	Result   *LoginResult `json:"result,omitempty"`
+	Error    any          `json:"error,omitempty"`
+	Canceled bool         `json:"canceled"`
+}
+
+// operation 登记中的操作及其取消函数
+type operation struct {
+	info   OperationInfo
+	seq    int
+	cancel context.CancelFunc
+}
+
+// OperationRegistry 由 App 持有，登记登录、检测等进行中的操作，便于按 ID 取消
+type OperationRegistry struct {
+	mu      sync.Mutex
+	next    int
+	running map[string]*operation
+}
+
+// NewOperationRegistry 创建操作登记表
+func NewOperationRegistry() *OperationRegistry {
+	return &OperationRegistry{running: map[string]*operation{}}
+}
+
+// Start 登记一个操作，返回操作 ID、操作使用的 context 以及结束时调用的 done。
+// 取消操作或 parent 结束时 context 随之结束，浏览器租约自动归还
+func (r *OperationRegistry) Start(parent context.Context, kind string) (string, context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	r.mu.Lock()
+	r.next++
+	id := fmt.Sprintf("%s-%d", kind, r.next)
+	r.running[id] = &operation{
+		info:   OperationInfo{ID: id, Kind: kind, StartedAt: time.Now().UnixMilli()},
+		seq:    r.next,
+		cancel: cancel,
+	}
+	r.mu.Unlock()
+
+	done := func() {
+		r.mu.Lock()
+		delete(r.running, id)
+		r.mu.Unlock()
+		cancel()
+	}
+	return id, ctx, done
+}
+
+// Cancel 取消进行中的操作
+func (r *OperationRegistry) Cancel(id string) error {
+	r.mu.Lock()
+	op, ok := r.running[id]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrOperationNotFound, id)
+	}
+
+	log.Printf("取消操作: %s", id)
+	op.cancel()
+	return nil
+}
+
+// List 返回进行中的操作，按开始顺序排列
+func (r *OperationRegistry) List() []OperationInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ops := make([]*operation, 0, len(r.running))
+	for _, op := range r.running {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].seq < ops[j].seq })
+
+	infos := make([]OperationInfo, len(ops))
+	for i, op := range ops {
+		infos[i] = op.info
+	}
+	return infos
+}
+
+// track 登记一个同步执行的操作，执行期间可通过 CancelOperation 取消，步骤进度通过事件发送
+func (a *App) track(kind string) (context.Context, func()) {
+	id, ctx, done := a.operations.Start(a.ops, kind)
+	return a.withStepEvents(ctx, id), done
+}
+
+// StartLogin 在后台使用默认账号登录并立即返回操作 ID，可通过 CancelOperation 取消。
+// 步骤进度通过 operation:step 事件发送，登录结束时发送 operation:finished 事件，携带登录结果或错误
+func (a *App) StartLogin() string {
+	id, ctx, done := a.operations.Start(a.ops, OperationLogin)
+	ctx = a.withStepEvents(ctx, id)
+	go func() {
+		defer done()
+
+		result, err := a.login(ctx, "")
+		event := OperationEvent{ID: id, Kind: OperationLogin, Result: result}
+		if err != nil {
+			event.Error = formatBindingError(err)
+			event.Canceled = ctx.Err() != nil
+		}
+		a.emit(operationFinishedEvent, event)
+	}()
+	return id
+}
+
+// CancelOperation 取消进行中的操作，浏览器操作立即中止，页面随之关闭
+func (a *App) CancelOperation(id string) error {
+	return a.operations.Cancel(id)
+}
+
+// ListOperations 返回进行中的操作
+func (a *App) ListOperations() []OperationInfo {
+	return a.operations.List()
+}
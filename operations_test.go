@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOperationRegistry(t *testing.T) {
+	registry := NewOperationRegistry()
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+
+	loginID, loginCtx, loginDone := registry.Start(parent, OperationLogin)
+	detectID, detectCtx, detectDone := registry.Start(parent, OperationDetect)
+	if loginID == detectID {
+		t.Fatalf("操作 ID 重复: %s", loginID)
+	}
+
+	ops := registry.List()
+	if len(ops) != 2 || ops[0].ID != loginID || ops[1].Kind != OperationDetect {
+		t.Errorf("进行中的操作不正确: %+v", ops)
+	}
+
+	// 取消一个操作不影响其他操作
+	if err := registry.Cancel(loginID); err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(loginCtx.Err(), context.Canceled) || detectCtx.Err() != nil {
+		t.Errorf("取消后 context 状态不正确: %v %v", loginCtx.Err(), detectCtx.Err())
+	}
+
+	// 操作结束后从登记表中移除，不能再取消
+	loginDone()
+	if err := registry.Cancel(loginID); !errors.Is(err, ErrOperationNotFound) {
+		t.Errorf("已结束的操作期望 %v，实际为 %v", ErrOperationNotFound, err)
+	}
+	if ops := registry.List(); len(ops) != 1 || ops[0].ID != detectID {
+		t.Errorf("进行中的操作不正确: %+v", ops)
+	}
+
+	// 程序退出时取消所有操作
+	cancelParent()
+	if detectCtx.Err() == nil {
+		t.Error("父 context 取消后操作没有结束")
+	}
+	detectDone()
+}
+
+func TestQueuedLoginCanceled(t *testing.T) {
+	app := NewApp()
+	defer app.cancelOps()
+
+	// 模拟进行中的登录
+	release, err := app.acquireLogin(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// 排队中的注销可以在操作列表中看到并取消
+	errs := make(chan error, 1)
+	go func() {
+		_, err := app.Logout()
+		errs <- err
+	}()
+	var id string
+	for deadline := time.Now().Add(2 * time.Second); id == "" && time.Now().Before(deadline); {
+		for _, op := range app.ListOperations() {
+			if op.Kind == OperationLogout {
+				id = op.ID
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if id == "" {
+		t.Fatal("排队中的注销没有出现在操作列表中")
+	}
+	if err := app.CancelOperation(id); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("期望 %v，实际为 %v", context.Canceled, err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("取消后注销仍在等待")
+	}
+
+	// 排队中的登录随 ctx 取消返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := app.login(ctx, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("期望 %v，实际为 %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"golang.org/x/net/html"
+)
+
+// Operator 认证页面提供的一项运营商服务
+type Operator struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Value string `json:"value"`
+	// Name 选择该服务时保存到配置中的名称，由 ListOperators 填写
+	Name string `json:"name,omitempty"`
+}
+
+// operatorAliases 运营商的常用名称与别名，每组第一个为认证页面上的标准名称
+var operatorAliases = [][]string{
+	{"校园网", "校园", "campus", "教育网", "cernet"},
+	{"联通", "中国联通", "unicom", "cucc"},
+	{"移动", "中国移动", "mobile", "cmcc"},
+	{"电信", "中国电信", "telecom", "ctcc"},
+}
+
+// legacyOperatorNames v3 及以前的配置使用字母表示运营商，对应扬州大学认证页面上的顺序
+var legacyOperatorNames = map[string]string{
+	"a": "校园网",
+	"b": "联通",
+	"c": "移动",
+	"d": "电信",
+}
+
+// defaultOperators 无法从认证页面读取服务列表时使用的扬州大学服务列表
+var defaultOperators = []Operator{
+	{ID: "_service_0", Label: "校园网", Value: "校园网"},
+	{ID: "_service_1", Label: "联通", Value: "联通"},
+	{ID: "_service_2", Label: "移动", Value: "移动"},
+	{ID: "_service_3", Label: "电信", Value: "电信"},
+}
+
+// operatorServicePrefix 锐捷认证页面中服务选项的 id 前缀
+const operatorServicePrefix = "_service_"
+
+// operatorKeys 返回运营商名称及其所有别名，均为小写
+func operatorKeys(name string) []string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, group := range operatorAliases {
+		for _, alias := range group {
+			if strings.ToLower(alias) == name {
+				keys := make([]string, len(group))
+				for i, alias := range group {
+					keys[i] = strings.ToLower(alias)
+				}
+				return keys
+			}
+		}
+	}
+	return []string{name}
+}
+
+// canonicalOperatorName 将别名转换为标准名称，不认识的名称原样返回
+func canonicalOperatorName(name string) string {
+	name = strings.TrimSpace(name)
+	for _, group := range operatorAliases {
+		for _, alias := range group {
+			if strings.EqualFold(alias, name) {
+				return group[0]
+			}
+		}
+	}
+	return name
+}
+
+// matchOperator 按名称或别名匹配服务的标签或值，先完全匹配，再匹配包含关系，
+// 例如 "移动" 可以匹配页面上的 "中国移动(100M)"
+func matchOperator(operators []Operator, name string) (Operator, bool) {
+	keys := operatorKeys(name)
+	for _, operator := range operators {
+		for _, key := range keys {
+			if strings.ToLower(operator.Label) == key || strings.ToLower(operator.Value) == key {
+				return operator, true
+			}
+		}
+	}
+	for _, operator := range operators {
+		label := strings.ToLower(operator.Label)
+		for _, key := range keys {
+			if key != "" && strings.Contains(label, key) {
+				return operator, true
+			}
+		}
+	}
+	return Operator{}, false
+}
+
+// parseOperators 从认证页面 HTML 中读取服务选项：id 以 _service_ 开头的元素，
+// 或 name/id 中包含 service 的下拉框中的选项
+func parseOperators(body io.Reader) ([]Operator, error) {
+	doc, err := html.Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("解析认证页面失败: %w", err)
+	}
+
+	var operators []Operator
+	var walk func(node *html.Node, inServiceSelect bool)
+	walk = func(node *html.Node, inServiceSelect bool) {
+		if node.Type == html.ElementNode {
+			id := htmlAttr(node, "id")
+			switch {
+			case strings.HasPrefix(id, operatorServicePrefix):
+				operators = append(operators, newOperator(id, htmlText(node), htmlAttr(node, "data-service")))
+				return
+			case node.Data == "select":
+				name := strings.ToLower(htmlAttr(node, "name") + " " + id)
+				inServiceSelect = strings.Contains(name, "service") || strings.Contains(name, "operator")
+			case node.Data == "option" && inServiceSelect:
+				if value := htmlAttr(node, "value"); value != "" {
+					operators = append(operators, newOperator(id, htmlText(node), value))
+				}
+				return
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child, inServiceSelect)
+		}
+	}
+	walk(doc, false)
+
+	return operators, nil
+}
+
+// newOperator 创建服务选项，没有单独的值时使用标签
+func newOperator(id, label, value string) Operator {
+	label = strings.TrimSpace(label)
+	value = strings.TrimSpace(value)
+	if value == "" {
+		value = label
+	}
+	return Operator{ID: id, Label: label, Value: value}
+}
+
+// htmlAttr 读取元素属性
+func htmlAttr(node *html.Node, name string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlText 读取元素内的全部文字
+func htmlText(node *html.Node) string {
+	var sb strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			sb.WriteString(node.Data)
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+	return strings.TrimSpace(sb.String())
+}
+
+// Operators 请求登录页并读取其中的服务列表
+func (c *EPortalClient) Operators(ctx context.Context) ([]Operator, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.loginURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("访问登录页面失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("登录页面返回状态码 %d", resp.StatusCode)
+	}
+
+	return parseOperators(resp.Body)
+}
+
+// scrapeOperators 从浏览器中已加载的认证页面读取服务列表，适用于由脚本生成服务列表的页面
+func scrapeOperators(page *rod.Page) ([]Operator, error) {
+	elements, err := page.Elements("[id^='" + operatorServicePrefix + "']")
+	if err != nil {
+		return nil, err
+	}
+
+	operators := make([]Operator, 0, len(elements))
+	for _, element := range elements {
+		id, _ := element.Attribute("id")
+		value, _ := element.Attribute("data-service")
+		text, _ := element.Text()
+		operators = append(operators, newOperator(derefString(id), text, derefString(value)))
+	}
+	return operators, nil
+}
+
+// derefString 读取可能为空的字符串指针
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// discoverOperators 读取认证页面的服务列表：先直接请求页面，读取不到时用浏览器加载，
+// 仍然读取不到时返回扬州大学的默认列表
+func discoverOperators(ctx context.Context, config *Config) []Operator {
+	if config.Webindex == "" {
+		return defaultOperators
+	}
+
+	if client, err := NewEPortalClient(config.Webindex, 10*time.Second); err == nil {
+		operators, err := client.Operators(ctx)
+		if err == nil && len(operators) > 0 {
+			return operators
+		}
+		log.Printf("未能从页面直接读取运营商列表: %v", err)
+	}
+
+	if config.Driver != "http" {
+		page, cleanup, err := openBrowserPage(ctx, config.Webindex, true)
+		if err == nil {
+			defer cleanup()
+			if err := NewSmartWaiter(ctx, page).WaitForPageLoad(10 * time.Second); err == nil {
+				if operators, err := scrapeOperators(page); err == nil && len(operators) > 0 {
+					return operators
+				}
+			}
+		} else {
+			log.Printf("启动浏览器读取运营商列表失败: %v", err)
+		}
+	}
+
+	log.Println("未能读取运营商列表，使用默认列表")
+	return defaultOperators
+}
+
+// ListOperators 列出默认账号认证页面提供的运营商服务
+func (a *App) ListOperators() ([]Operator, error) {
+	ctx, done := a.track(OperationOperators)
+	defer done()
+
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return nil, err
+	}
+	return nameOperators(discoverOperators(ctx, config)), nil
+}
+
+// nameOperators 为服务填写保存到配置中的名称：依次尝试标准名称、标签与值，
+// 取第一个未被使用、且经 matchOperator 匹配回该服务的名称，保证每个服务的名称唯一。
+// 例如 "中国移动(100M)" 与 "移动" 同时存在时，前者使用标签，后者使用标准名称
+func nameOperators(operators []Operator) []Operator {
+	named := make([]Operator, len(operators))
+	used := map[string]bool{}
+	for i, operator := range operators {
+		// 值在页面中唯一，其他名称都不可用时使用值
+		name := operator.Value
+		for _, candidate := range []string{operatorName(operator.Label), operator.Label, operator.Value} {
+			if candidate == "" || used[candidate] {
+				continue
+			}
+			if matched, ok := matchOperator(operators, candidate); ok && matched == operator {
+				name = candidate
+				break
+			}
+		}
+		used[name] = true
+		operator.Name = name
+		named[i] = operator
+	}
+	return named
+}
+
+// operatorName 返回标签对应的运营商标准名称，标签与别名相同或包含别名时视为该运营商，否则原样返回
+func operatorName(label string) string {
+	lower := strings.ToLower(label)
+	for _, group := range operatorAliases {
+		for _, alias := range group {
+			if strings.Contains(lower, strings.ToLower(alias)) {
+				return group[0]
+			}
+		}
+	}
+	return label
+}
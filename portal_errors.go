@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 认证页面拒绝登录的原因
+var (
+	ErrBadCredentials      = errors.New("账号或密码错误")
+	ErrAccountSuspended    = errors.New("账号已欠费或停用")
+	ErrDeviceLimit         = errors.New("在线设备数已达上限")
+	ErrOperatorUnavailable = errors.New("运营商服务不可用或未绑定")
+	ErrAlreadyOnline       = errors.New("账号已在线")
+)
+
+// PortalError 认证页面返回的错误，Kind 为上面定义的错误之一
+type PortalError struct {
+	Kind    error
+	Message string
+}
+
+func (e *PortalError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Kind, e.Message)
+}
+
+func (e *PortalError) Unwrap() error {
+	return e.Kind
+}
+
+// portalErrorRules 提示文字关键词与错误类型的对应关系，按顺序匹配。
+// 账号密码错误最先匹配，如"运营商账号密码错误"；停用、过期等关键词与英文关键词都取完整短语，
+// 避免"页面已过期"这类与账号无关的提示被当作无法重试的错误
+var portalErrorRules = []struct {
+	kind     error
+	keywords []string
+}{
+	{ErrBadCredentials, []string{"密码错误", "密码不正确", "用户不存在", "用户名或密码", "账号或密码", "账号不存在", "用户名不存在",
+		"password is error", "password error", "wrong password", "incorrect password", "invalid password",
+		"user not exist", "user does not exist", "user not found", "ldap_bind"}},
+	{ErrAlreadyOnline, []string{"已在线", "已经在线", "重复认证", "重复登录", "already online", "already_online"}},
+	{ErrDeviceLimit, []string{"在线数", "终端数", "设备数", "在线设备", "达到上限", "超过上限", "已达上限", "最大在线", "超过限额",
+		"exceeds the limit", "exceed the limit", "more than the limit", "online limit", "max online"}},
+	{ErrAccountSuspended, []string{"欠费", "余额不足", "账号已停机", "账户已停机",
+		"账号已停用", "账户已停用", "用户已停用", "账号已冻结", "账户已冻结", "账号被冻结", "账户被冻结",
+		"账号已禁用", "账户已禁用", "用户已禁用", "账号被禁用", "账户被禁用",
+		"账号已过期", "账户已过期", "用户已过期", "账号已暂停", "账户已暂停", "账号暂停使用",
+		"arrear", "account suspended", "account is suspended", "user suspended", "user is suspended",
+		"account disabled", "account is disabled", "user disabled", "user is disabled"}},
+	{ErrOperatorUnavailable, []string{"服务不存在", "服务不可用", "未开通", "未绑定", "无此服务",
+		"service is not available", "service not available", "service unavailable", "no such service"}},
+}
+
+// classifyPortalMessage 将认证页面的提示文字归类为对应的错误，无法识别时返回 nil
+func classifyPortalMessage(message string) error {
+	lower := strings.ToLower(message)
+	for _, rule := range portalErrorRules {
+		for _, keyword := range rule.keywords {
+			if strings.Contains(lower, keyword) {
+				return &PortalError{Kind: rule.kind, Message: message}
+			}
+		}
+	}
+	return nil
+}
+
+// portalFailure 将认证失败的提示文字转换为错误，无法归类时返回通用的认证失败
+func portalFailure(message string) error {
+	if err := classifyPortalMessage(message); err != nil {
+		return err
+	}
+	return fmt.Errorf("认证失败: %s", message)
+}
+
+// isPermanentLoginError 判断错误是否由认证页面明确拒绝，重试或更换登录方式也不会成功
+func isPermanentLoginError(err error) bool {
+	var portalErr *PortalError
+	return errors.As(err, &portalErr)
+}
+
+// portalErrorCodes 错误类型对应的代码，供前端与命令行 JSON 输出区分
+var portalErrorCodes = map[error]string{
+	ErrBadCredentials:      "bad_credentials",
+	ErrAccountSuspended:    "account_suspended",
+	ErrDeviceLimit:         "device_limit",
+	ErrOperatorUnavailable: "operator_unavailable",
+	ErrAlreadyOnline:       "already_online",
+}
+
+// portalErrorCode 返回错误对应的代码，非认证页面拒绝的错误返回空字符串
+func portalErrorCode(err error) string {
+	var portalErr *PortalError
+	if !errors.As(err, &portalErr) {
+		return ""
+	}
+	return portalErrorCodes[portalErr.Kind]
+}
+
+// formatBindingError 绑定方法返回错误时，认证页面拒绝的错误附带代码传给前端，其余错误仍为文字
+func formatBindingError(err error) any {
+	code := portalErrorCode(err)
+	if code == "" {
+		return err.Error()
+	}
+	return map[string]string{"code": code, "message": err.Error()}
+}
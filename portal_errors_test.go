@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyPortalMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    error
+	}{
+		// 锐捷 ePortal
+		{"用户名或密码错误", ErrBadCredentials},
+		{"密码不正确，请重新输入", ErrBadCredentials},
+		{"用户不存在", ErrBadCredentials},
+		{"运营商账号密码错误", ErrBadCredentials},
+		{"该账号已在线，请勿重复认证", ErrAlreadyOnline},
+		{"您的账号在线设备数已达上限", ErrDeviceLimit},
+		{"您的账户已欠费，请充值后再试", ErrAccountSuspended},
+		{"所选服务不可用，请选择其他服务", ErrOperatorUnavailable},
+		{"该账号未开通此服务", ErrOperatorUnavailable},
+		{"您的账号已暂停，请联系管理员", ErrAccountSuspended},
+		{"认证设备响应超时，请稍后再试", nil},
+		{"系统维护中，认证服务暂停，请稍后再试", nil},
+		{"用户已不在线", nil},
+		{"页面已过期，请刷新后重试", nil},
+		{"该服务已停用", nil},
+		{"您的账号已过期，请续费", ErrAccountSuspended},
+		{"账户已停用", ErrAccountSuspended},
+
+		// 深澜 Srun
+		{"E2531: User not found.", ErrBadCredentials},
+		{"E2553: Password is error.", ErrBadCredentials},
+		{"E2901: (Third party 1)bind_user2: ldap_bind error", ErrBadCredentials},
+		{"E2620: You are already online.", ErrAlreadyOnline},
+		{"ip_already_online_error", ErrAlreadyOnline},
+		{"E2542: The number of online users exceeds the limit.", ErrDeviceLimit},
+		{"E2616: Arrearage users.", ErrAccountSuspended},
+		{"E2606: User is disabled.", ErrAccountSuspended},
+		{"E2532: The two authentication interval cannot be less than 10 seconds.", nil},
+		{"INFO failed, BAS respond timeout.", nil},
+		{"Service temporarily overloaded, rate limit applied", nil},
+		{"Forgot password? Contact the service desk", nil},
+		{"Your account is suspended.", ErrAccountSuspended},
+		{"Service suspended for maintenance", nil},
+		{`<input type="submit" value="Login" disabled>`, nil},
+		{"button.disabled = true", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			err := classifyPortalMessage(tt.message)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("不应归类，实际为 %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("期望 %v，实际为 %v", tt.want, err)
+			}
+		})
+	}
+}
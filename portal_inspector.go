@@ -0,0 +1,177 @@
+package main
+
+import (
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// successPageKeywords 认证成功后跳转页面的URL特征
+var successPageKeywords = []string{"success.jsp", "success.html", "/success"}
+
+// portalErrorSelectors 认证页面显示错误提示的元素
+var portalErrorSelectors = []string{"#errorInfo_center", "#errorInfo_bottom", ".errorInfo"}
+
+// portalSuccessSelectors 认证成功页面显示提示信息的元素
+var portalSuccessSelectors = []string{"#userMessage", ".success", "title"}
+
+// PortalInspector 提交登录后检查认证页面：成功页面、错误提示以及 alert 弹窗
+type PortalInspector struct {
+	page *rod.Page
+
+	mu      sync.Mutex
+	dialogs []string
+	stop    func()
+}
+
+// NewPortalInspector 开始监听页面弹窗，需在点击登录之前创建，使用完毕后调用 Close
+func NewPortalInspector(page *rod.Page) *PortalInspector {
+	inspector := &PortalInspector{page: page}
+
+	listenPage, cancel := page.WithCancel()
+	inspector.stop = cancel
+	wait := listenPage.EachEvent(func(e *proto.PageJavascriptDialogOpening) {
+		inspector.mu.Lock()
+		inspector.dialogs = append(inspector.dialogs, strings.TrimSpace(e.Message))
+		inspector.mu.Unlock()
+		log.Printf("认证页面弹窗: %s", e.Message)
+
+		// 弹窗不关闭会阻塞页面脚本
+		go func() {
+			_ = proto.PageHandleJavaScriptDialog{Accept: true}.Call(page)
+		}()
+	})
+	go wait()
+
+	return inspector
+}
+
+// Close 停止监听页面弹窗
+func (pi *PortalInspector) Close() {
+	pi.stop()
+}
+
+// lastDialog 返回最近一次弹窗的文字
+func (pi *PortalInspector) lastDialog() string {
+	pi.mu.Lock()
+	defer pi.mu.Unlock()
+
+	if len(pi.dialogs) == 0 {
+		return ""
+	}
+	return pi.dialogs[len(pi.dialogs)-1]
+}
+
+// Verify 点击登录后确认认证结果：跳转到成功页面或网络已连通视为成功，
+// 页面提示或弹窗可识别时返回对应的错误，页面的 context 结束时停止等待
+func (pi *PortalInspector) Verify(result *LoginResult) error {
+	deadline := time.Now().Add(loginVerifyTimeout)
+	for time.Now().Before(deadline) {
+		if info, err := pi.page.Info(); err == nil {
+			result.FinalURL = info.URL
+			if isSuccessPage(info.URL) {
+				result.Outcome = LoginOutcomeSuccess
+				result.Message = readPortalMessage(pi.page, portalSuccessSelectors)
+				if u, err := url.Parse(info.URL); err == nil && u.Query().Get("userIndex") != "" {
+					rememberUserIndex(u.Query().Get("userIndex"))
+				}
+				log.Printf("已跳转到认证成功页面: %s", info.URL)
+				return nil
+			}
+		}
+
+		if message := pi.lastDialog(); message != "" {
+			result.Message = message
+			if err := classifyPortalMessage(message); err != nil {
+				return err
+			}
+		}
+
+		if message := readPortalMessage(pi.page, portalErrorSelectors); message != "" {
+			result.Message = message
+			return portalFailure(message)
+		}
+
+		if err := sleepContext(pi.page.GetContext(), 500*time.Millisecond); err != nil {
+			return err
+		}
+	}
+
+	// 没有跳转到成功页面时，通过连通性检测确认
+	if connected, _, err := CheckConnectivity(pi.page.GetContext(), 5*time.Second); err == nil && connected {
+		result.Outcome = LoginOutcomeSuccess
+		log.Println("连通性检测通过，认证成功")
+		return nil
+	}
+
+	result.Outcome = LoginOutcomeUnverified
+	return ErrLoginUnverified
+}
+
+// readPortalMessage 读取页面上显示的提示信息，不等待元素出现
+func readPortalMessage(page *rod.Page, selectors []string) string {
+	for _, selector := range selectors {
+		has, element, err := page.Has(selector)
+		if err != nil || !has {
+			continue
+		}
+		if visible, _ := element.Visible(); !visible && selector != "title" {
+			continue
+		}
+		text, err := element.Text()
+		if err == nil && strings.TrimSpace(text) != "" {
+			return strings.TrimSpace(text)
+		}
+	}
+	return ""
+}
+
+// VerifyLogout 点击下线后确认结果：离开成功页面回到登录表单，或网络已断开视为成功
+func (pi *PortalInspector) VerifyLogout(result *LoginResult) error {
+	deadline := time.Now().Add(loginVerifyTimeout)
+	for time.Now().Before(deadline) {
+		if message := pi.lastDialog(); message != "" {
+			result.Message = message
+		}
+
+		if info, err := pi.page.Info(); err == nil {
+			result.FinalURL = info.URL
+			if !isSuccessPage(info.URL) {
+				if has, _, _ := pi.page.Has("input[type='password']"); has {
+					result.Outcome = LoginOutcomeSuccess
+					log.Printf("已返回认证页面: %s", info.URL)
+					return nil
+				}
+			}
+		}
+
+		if err := sleepContext(pi.page.GetContext(), 500*time.Millisecond); err != nil {
+			return err
+		}
+	}
+
+	// 仍停留在原页面时，通过连通性检测确认是否已下线
+	if connected, _, err := CheckConnectivity(pi.page.GetContext(), 5*time.Second); err == nil && !connected {
+		result.Outcome = LoginOutcomeSuccess
+		log.Println("连通性检测显示网络已断开，注销成功")
+		return nil
+	}
+
+	result.Outcome = LoginOutcomeUnverified
+	return ErrLogoutUnverified
+}
+
+// isSuccessPage 判断URL是否为认证成功页面
+func isSuccessPage(pageURL string) bool {
+	for _, keyword := range successPageKeywords {
+		if strings.Contains(pageURL, keyword) {
+			return true
+		}
+	}
+	return false
+}
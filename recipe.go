@@ -0,0 +1,554 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/input"
+	"github.com/go-rod/rod/lib/proto"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed recipes/*.json
+var builtinRecipes embed.FS
+
+// defaultRecipeName 没有指定也没有匹配的登录脚本时使用的内置脚本
+const defaultRecipeName = "yzu"
+
+// builtinRecipeSource 内置登录脚本的来源
+const builtinRecipeSource = "builtin"
+
+// recipeDirName 配置目录下存放自定义登录脚本的子目录
+const recipeDirName = "recipes"
+
+// Recipe 声明式的登录脚本，描述浏览器登录的每一步。
+// 其他学校或认证页面改版时在配置目录的 recipes 下放入脚本文件即可，无需重新编译
+type Recipe struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Match 认证地址包含其中任一字符串时自动使用该脚本
+	Match []string `json:"match,omitempty" yaml:"match,omitempty"`
+	// Vendor 适用的认证系统厂商，没有 match 命中时按识别出的厂商选择
+	Vendor PortalVendor `json:"vendor,omitempty" yaml:"vendor,omitempty"`
+	Steps  []RecipeStep `json:"steps" yaml:"steps"`
+	// Source 脚本来源，内置脚本为 builtin，其他为文件路径
+	Source string `json:"source" yaml:"-"`
+}
+
+// RecipeStep 登录脚本中的一步
+type RecipeStep struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Action 步骤类型: wait、fill、click、press、select、assert、eval
+	Action string `json:"action" yaml:"action"`
+	// Selectors 依次尝试的选择器，以 / 或 ( 开头的视为 XPath
+	Selectors []string `json:"selectors,omitempty" yaml:"selectors,omitempty"`
+	// Open select 步骤在读取选项前点击的下拉框
+	Open []string `json:"open,omitempty" yaml:"open,omitempty"`
+	// Value fill 输入的内容或 select 选择的选项，可使用 {{username}}、{{password}}、{{operator}}
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// Key press 步骤按下的键
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// Text assert 步骤要求元素或页面包含的文字
+	Text string `json:"text,omitempty" yaml:"text,omitempty"`
+	// Script eval 步骤执行的 JavaScript 函数，参数为包含 username、password、operator 的对象，返回 false 视为失败
+	Script     string `json:"script,omitempty" yaml:"script,omitempty"`
+	MaxRetries int    `json:"max_retries,omitempty" yaml:"max_retries,omitempty"`
+	// Timeout 单次尝试的超时，如 3s，超时后中止该次尝试，重试间隔为其四分之一
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Delay 执行前等待的时间，给页面脚本留出反应时间，不计入超时
+	Delay string `json:"delay,omitempty" yaml:"delay,omitempty"`
+}
+
+// 步骤未填写时的默认值
+const (
+	defaultRecipeRetries = 2
+	defaultRecipeTimeout = 3 * time.Second
+)
+
+// recipeAction 执行一种步骤，page 已绑定 ctx，ctx 在步骤超时或操作取消时结束
+type recipeAction func(ctx context.Context, page *rod.Page, step *RecipeStep, vars map[string]string, timeout time.Duration) error
+
+// recipeActions 所有可用的步骤类型
+var recipeActions = map[string]recipeAction{
+	"wait":   recipeWait,
+	"fill":   recipeFill,
+	"click":  recipeClick,
+	"press":  recipePress,
+	"select": recipeSelect,
+	"assert": recipeAssert,
+	"eval":   recipeEval,
+}
+
+// recipeKeys press 步骤可以按下的键
+var recipeKeys = map[string]input.Key{
+	"Enter":  input.Enter,
+	"Tab":    input.Tab,
+	"Escape": input.Escape,
+	"Space":  input.Space,
+}
+
+// Validate 校验脚本，加载时调用，避免登录进行到一半才发现脚本写错
+func (r *Recipe) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("脚本名称不能为空")
+	}
+	if len(r.Steps) == 0 {
+		return fmt.Errorf("脚本 %s 没有步骤", r.Name)
+	}
+
+	for i, step := range r.Steps {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("脚本 %s 第 %d 步: %w", r.Name, i+1, err)
+		}
+	}
+	return nil
+}
+
+// validate 校验单个步骤
+func (s *RecipeStep) validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("步骤名称不能为空")
+	}
+	if _, ok := recipeActions[s.Action]; !ok {
+		return fmt.Errorf("未知的步骤类型: %s", s.Action)
+	}
+
+	switch s.Action {
+	case "wait":
+	case "eval":
+		if s.Script == "" {
+			return fmt.Errorf("eval 步骤需要 script")
+		}
+	case "assert":
+		if len(s.Selectors) == 0 && s.Text == "" {
+			return fmt.Errorf("assert 步骤需要 selectors 或 text")
+		}
+	default:
+		if len(s.Selectors) == 0 {
+			return fmt.Errorf("%s 步骤需要 selectors", s.Action)
+		}
+	}
+	if s.Action == "press" {
+		if _, ok := recipeKeys[s.Key]; !ok {
+			return fmt.Errorf("不支持的按键: %s", s.Key)
+		}
+	}
+
+	for _, value := range []string{s.Timeout, s.Delay} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("时间格式无效: %s", value)
+		}
+	}
+	return nil
+}
+
+// parseRecipeDuration 解析已校验过的时间，未填写时使用默认值
+func parseRecipeDuration(value string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	return fallback
+}
+
+// LoginSteps 将脚本转换为 ExecuteLoginStep 可执行的登录步骤
+func (r *Recipe) LoginSteps() []LoginStep {
+	steps := make([]LoginStep, len(r.Steps))
+	for i := range r.Steps {
+		step := r.Steps[i]
+		action := recipeActions[step.Action]
+		timeout := parseRecipeDuration(step.Timeout, defaultRecipeTimeout)
+		delay := parseRecipeDuration(step.Delay, 0)
+
+		retries := step.MaxRetries
+		if retries <= 0 {
+			retries = defaultRecipeRetries
+		}
+
+		steps[i] = LoginStep{
+			Name:        step.Name,
+			Description: step.Description,
+			Execute: func(ctx context.Context, page *rod.Page, config *Config) error {
+				return action(ctx, page, &step, recipeVars(config), timeout)
+			},
+			MaxRetries: retries,
+			Timeout:    timeout,
+			Delay:      delay,
+		}
+	}
+	return steps
+}
+
+// recipeVars 脚本中可以引用的变量
+func recipeVars(config *Config) map[string]string {
+	return map[string]string{
+		"username": config.Countindex,
+		"password": config.Passwordindex,
+		"operator": config.Operatorindex,
+	}
+}
+
+// expandRecipeValue 替换值中的 {{变量}}
+func expandRecipeValue(value string, vars map[string]string) string {
+	for name, v := range vars {
+		value = strings.ReplaceAll(value, "{{"+name+"}}", v)
+	}
+	return value
+}
+
+// recipeElements 按选择器查找元素，不等待
+func recipeElements(page *rod.Page, selector string) (rod.Elements, error) {
+	if strings.HasPrefix(selector, "/") || strings.HasPrefix(selector, "(") {
+		return page.ElementsX(selector)
+	}
+	return page.Elements(selector)
+}
+
+// findRecipeElement 按选择器顺序返回第一个可见的元素
+func findRecipeElement(page *rod.Page, selectors []string) (*rod.Element, error) {
+	for _, selector := range selectors {
+		elements, err := recipeElements(page, selector)
+		if err != nil {
+			continue
+		}
+		for _, element := range elements {
+			if visible, _ := element.Visible(); visible {
+				log.Printf("通过选择器找到元素: %s", selector)
+				return element, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("找不到元素: %s", strings.Join(selectors, ", "))
+}
+
+// recipeWait 没有选择器时等待页面加载完成，否则等待任一元素出现
+func recipeWait(ctx context.Context, page *rod.Page, step *RecipeStep, vars map[string]string, timeout time.Duration) error {
+	if len(step.Selectors) == 0 {
+		return NewSmartWaiter(ctx, page).WaitForPageLoad(timeout)
+	}
+
+	// 步骤超时由 ctx 控制
+	for {
+		if _, err := findRecipeElement(page, step.Selectors); err == nil {
+			return nil
+		}
+		if err := sleepContext(ctx, 200*time.Millisecond); err != nil {
+			return fmt.Errorf("等待元素超时: %s: %w", strings.Join(step.Selectors, ", "), err)
+		}
+	}
+}
+
+// recipeFill 在输入框中输入内容
+func recipeFill(ctx context.Context, page *rod.Page, step *RecipeStep, vars map[string]string, timeout time.Duration) error {
+	element, err := findRecipeElement(page, step.Selectors)
+	if err != nil {
+		return err
+	}
+	return fillInputElement(element, expandRecipeValue(step.Value, vars), step.Name)
+}
+
+// recipeClick 点击元素
+func recipeClick(ctx context.Context, page *rod.Page, step *RecipeStep, vars map[string]string, timeout time.Duration) error {
+	element, err := findRecipeElement(page, step.Selectors)
+	if err != nil {
+		return err
+	}
+	return element.Click(proto.InputMouseButtonLeft, 1)
+}
+
+// recipePress 在元素上按下按键
+func recipePress(ctx context.Context, page *rod.Page, step *RecipeStep, vars map[string]string, timeout time.Duration) error {
+	element, err := findRecipeElement(page, step.Selectors)
+	if err != nil {
+		return err
+	}
+	return element.Type(recipeKeys[step.Key])
+}
+
+// recipeSelect 展开下拉框后按名称或别名选择选项，不依赖选项的顺序。
+// 选项可以是任意可点击的元素，也可以是下拉框中的 option；未配置要选择的值时跳过，保留页面的默认选项
+func recipeSelect(ctx context.Context, page *rod.Page, step *RecipeStep, vars map[string]string, timeout time.Duration) error {
+	value := expandRecipeValue(step.Value, vars)
+	if value == "" {
+		log.Printf("未配置 %s 的值，跳过选择", step.Name)
+		return nil
+	}
+
+	if len(step.Open) > 0 {
+		if element, err := findRecipeElement(page, step.Open); err == nil {
+			if err := element.Click(proto.InputMouseButtonLeft, 1); err == nil {
+				// 等待下拉选项展开
+				if err := sleepContext(ctx, 500*time.Millisecond); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	var (
+		operators []Operator
+		elements  []*rod.Element
+	)
+	for _, selector := range step.Selectors {
+		found, err := recipeElements(page, selector)
+		if err != nil {
+			continue
+		}
+		for _, element := range found {
+			id, _ := element.Attribute("id")
+			value, _ := element.Attribute("data-service")
+			if value == nil {
+				value, _ = element.Attribute("value")
+			}
+			text, _ := element.Text()
+			operators = append(operators, newOperator(derefString(id), text, derefString(value)))
+			elements = append(elements, element)
+		}
+	}
+	if len(operators) == 0 {
+		return fmt.Errorf("找不到可选择的选项: %s", strings.Join(step.Selectors, ", "))
+	}
+
+	operator, ok := matchOperator(operators, value)
+	if !ok {
+		return &PortalError{Kind: ErrOperatorUnavailable, Message: fmt.Sprintf("认证页面没有运营商 %s", value)}
+	}
+	element := elements[0]
+	for i := range operators {
+		if operators[i] == operator {
+			element = elements[i]
+			break
+		}
+	}
+
+	if tag, err := element.Property("tagName"); err == nil && strings.EqualFold(tag.Str(), "option") {
+		parent, err := element.Parent()
+		if err != nil {
+			return err
+		}
+		if err := parent.Select([]string{fmt.Sprintf("option[value=%q]", operator.Value)}, true, rod.SelectorTypeCSSSector); err != nil {
+			return err
+		}
+	} else if err := element.Click(proto.InputMouseButtonLeft, 1); err != nil {
+		return err
+	}
+
+	log.Printf("成功选择: %s", operator.Label)
+	return nil
+}
+
+// recipeAssert 检查元素存在，指定 text 时还要求元素（没有选择器时为整个页面）包含该文字
+func recipeAssert(ctx context.Context, page *rod.Page, step *RecipeStep, vars map[string]string, timeout time.Duration) error {
+	text := expandRecipeValue(step.Text, vars)
+
+	var element *rod.Element
+	var err error
+	if len(step.Selectors) > 0 {
+		element, err = findRecipeElement(page, step.Selectors)
+	} else {
+		element, err = page.Element("body")
+	}
+	if err != nil {
+		return fmt.Errorf("断言失败: %w", err)
+	}
+
+	if text != "" {
+		content, err := element.Text()
+		if err != nil {
+			return fmt.Errorf("断言失败: %w", err)
+		}
+		if !strings.Contains(content, text) {
+			return fmt.Errorf("断言失败: 页面不包含 %q", text)
+		}
+	}
+	return nil
+}
+
+// recipeEval 在页面中执行脚本
+func recipeEval(ctx context.Context, page *rod.Page, step *RecipeStep, vars map[string]string, timeout time.Duration) error {
+	result, err := page.Timeout(timeout).Eval(step.Script, vars)
+	if err != nil {
+		return fmt.Errorf("执行脚本失败: %w", err)
+	}
+	if result.Type == proto.RuntimeRemoteObjectTypeBoolean && !result.Value.Bool() {
+		return fmt.Errorf("脚本返回 false")
+	}
+	return nil
+}
+
+// parseRecipe 按扩展名解析 JSON 或 YAML 格式的脚本，拒绝未知字段以便发现拼写错误
+func parseRecipe(name string, data []byte) (*Recipe, error) {
+	var recipe Recipe
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&recipe); err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", name, err)
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&recipe); err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("不支持的脚本格式: %s", name)
+	}
+
+	if err := recipe.Validate(); err != nil {
+		return nil, err
+	}
+	return &recipe, nil
+}
+
+// recipeDir 返回自定义登录脚本目录
+func recipeDir() string {
+	return filepath.Join(ConfigDir(), recipeDirName)
+}
+
+// LoadRecipes 读取配置目录 recipes 下的自定义脚本与内置脚本，自定义脚本在前，
+// 与内置脚本同名时替换内置脚本。无法解析的文件记录日志后跳过
+func LoadRecipes() ([]*Recipe, error) {
+	var recipes []*Recipe
+	names := map[string]bool{}
+
+	entries, err := os.ReadDir(recipeDir())
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("读取登录脚本目录失败: %v", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".json", ".yaml", ".yml":
+		default:
+			continue
+		}
+
+		path := filepath.Join(recipeDir(), entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("读取登录脚本失败: %v", err)
+			continue
+		}
+		recipe, err := parseRecipe(entry.Name(), data)
+		if err != nil {
+			log.Printf("跳过登录脚本 %s: %v", path, err)
+			continue
+		}
+		if names[recipe.Name] {
+			log.Printf("跳过登录脚本 %s: 名称 %s 重复", path, recipe.Name)
+			continue
+		}
+		recipe.Source = path
+		recipes = append(recipes, recipe)
+		names[recipe.Name] = true
+	}
+
+	builtins, err := fs.Glob(builtinRecipes, "recipes/*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range builtins {
+		data, err := builtinRecipes.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		recipe, err := parseRecipe(path, data)
+		if err != nil {
+			return nil, fmt.Errorf("内置登录脚本无效: %w", err)
+		}
+		if names[recipe.Name] {
+			continue
+		}
+		recipe.Source = builtinRecipeSource
+		recipes = append(recipes, recipe)
+		names[recipe.Name] = true
+	}
+
+	return recipes, nil
+}
+
+// selectRecipe 选择登录脚本：配置中指定了名称时使用该脚本，否则依次使用第一个 match 与认证地址匹配的脚本、
+// 第一个 vendor 与识别出的认证系统相同的脚本，都没有时使用内置的 yzu 脚本
+func selectRecipe(config *Config, vendor PortalVendor) (*Recipe, error) {
+	recipes, err := LoadRecipes()
+	if err != nil {
+		return nil, err
+	}
+
+	name := config.Recipe
+	if name == "" {
+		for _, recipe := range recipes {
+			for _, pattern := range recipe.Match {
+				if pattern != "" && strings.Contains(config.Webindex, pattern) {
+					return recipe, nil
+				}
+			}
+		}
+		if vendor != "" && vendor != VendorUnknown {
+			for _, recipe := range recipes {
+				if recipe.Vendor == vendor {
+					return recipe, nil
+				}
+			}
+			log.Printf("没有适用于 %s 的登录脚本，使用 %s", vendor, defaultRecipeName)
+		}
+		name = defaultRecipeName
+	}
+
+	for _, recipe := range recipes {
+		if recipe.Name == name {
+			return recipe, nil
+		}
+	}
+	return nil, fmt.Errorf("找不到登录脚本: %s", name)
+}
+
+// configRecipe 按配置与已缓存的认证系统识别结果选择登录脚本，不发起请求
+func configRecipe(config *Config) (*Recipe, error) {
+	var vendor PortalVendor
+	if fingerprint := cachedPortalFingerprint(config.Webindex); fingerprint != nil {
+		vendor = fingerprint.Vendor
+	}
+	return selectRecipe(config, vendor)
+}
+
+// GetLoginSteps 返回浏览器模拟登录将依次执行的步骤，前端据此显示登录进度。
+// 其他驱动的步骤事先无法确定，返回空列表，前端在收到步骤事件时逐个追加
+func (a *App) GetLoginSteps() ([]RecipeStep, error) {
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return nil, err
+	}
+	if config.Driver != "rod" {
+		return []RecipeStep{}, nil
+	}
+	recipe, err := configRecipe(config)
+	if err != nil {
+		return nil, err
+	}
+	return recipe.Steps, nil
+}
+
+// ListRecipes 列出内置与自定义的登录脚本
+func (a *App) ListRecipes() ([]*Recipe, error) {
+	return LoadRecipes()
+}
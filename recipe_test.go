@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestRecipe 在配置目录的 recipes 下写入脚本文件，测试结束后删除
+func writeTestRecipe(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(recipeDir(), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(recipeDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(path) })
+}
+
+func TestBuiltinRecipe(t *testing.T) {
+	recipes, err := LoadRecipes()
+	if err != nil {
+		t.Fatalf("读取登录脚本失败: %v", err)
+	}
+	if len(recipes) != 1 || recipes[0].Name != defaultRecipeName || recipes[0].Source != builtinRecipeSource {
+		t.Fatalf("内置登录脚本不正确: %+v", recipes)
+	}
+
+	steps := recipes[0].LoginSteps()
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Name)
+		if step.MaxRetries <= 0 || step.Timeout <= 0 {
+			t.Errorf("步骤 %s 没有补全重试次数或超时: %+v", step.Name, step)
+		}
+	}
+	want := "等待页面加载,输入用户名,输入密码,提交表单,选择运营商,确认登录"
+	if strings.Join(names, ",") != want {
+		t.Errorf("登录步骤为 %v", names)
+	}
+}
+
+func TestSelectRecipe(t *testing.T) {
+	writeTestRecipe(t, "srun.yaml", `
+name: 深澜
+match: ["10.0.0.1"]
+steps:
+  - name: 输入账号
+    action: fill
+    selectors: ["#username"]
+    value: "{{username}}"
+  - name: 点击登录
+    action: click
+    selectors: ["#login-account"]
+    timeout: 5s
+`)
+	writeTestRecipe(t, "broken.json", `{"name": "broken", "steps": [{"name": "跳转", "action": "goto"}]}`)
+	writeTestRecipe(t, "typo.yaml", "name: typo\nsteps:\n  - name: 点击\n    action: click\n    selector: ['#a']\n")
+
+	tests := []struct {
+		name     string
+		webindex string
+		recipe   string
+		want     string
+		wantErr  bool
+	}{
+		{name: "按认证地址匹配", webindex: "http://10.0.0.1/srun_portal_pc", want: "深澜"},
+		{name: "没有匹配时使用内置脚本", webindex: "http://10.1.1.1/eportal/index.jsp", want: defaultRecipeName},
+		{name: "指定名称", webindex: "http://10.0.0.1/srun_portal_pc", recipe: defaultRecipeName, want: defaultRecipeName},
+		{name: "无效脚本被跳过", webindex: "http://10.1.1.1/", recipe: "broken", wantErr: true},
+		{name: "未知字段视为无效", webindex: "http://10.1.1.1/", recipe: "typo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.Webindex = tt.webindex
+			config.Recipe = tt.recipe
+
+			recipe, err := selectRecipe(config, "")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("期望错误，实际选择了 %s", recipe.Name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("选择登录脚本失败: %v", err)
+			}
+			if recipe.Name != tt.want {
+				t.Errorf("选择了 %s，期望 %s", recipe.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecipeOverridesBuiltin(t *testing.T) {
+	writeTestRecipe(t, "yzu.json", `{"name": "yzu", "steps": [{"name": "等待", "action": "wait", "timeout": "1s"}]}`)
+
+	recipe, err := selectRecipe(DefaultConfig(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipe.Source == builtinRecipeSource || len(recipe.Steps) != 1 {
+		t.Errorf("自定义脚本没有替换内置脚本: %+v", recipe)
+	}
+}
+
+func TestRecipeSelectWithoutValue(t *testing.T) {
+	recipe, err := selectRecipe(DefaultConfig(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var step *RecipeStep
+	for i := range recipe.Steps {
+		if recipe.Steps[i].Action == "select" {
+			step = &recipe.Steps[i]
+		}
+	}
+	if step == nil {
+		t.Fatal("内置脚本没有选择运营商的步骤")
+	}
+
+	// 未配置运营商时跳过选择，不访问页面，也不返回无法重试的错误
+	if err := recipeSelect(context.Background(), nil, step, map[string]string{"operator": ""}, time.Second); err != nil {
+		t.Errorf("未配置运营商时应跳过选择，实际为 %v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+// secretService 系统钥匙串中使用的服务名
+const secretService = "YzuAutologin"
+
+// secretPassphraseEnv 用户口令环境变量，设置后文件存储使用口令派生的密钥
+const secretPassphraseEnv = "YZU_AUTOLOGIN_PASSPHRASE"
+
+// secretBackendEnv 设置为 file 时强制使用加密文件存储
+const secretBackendEnv = "YZU_AUTOLOGIN_SECRET_STORE"
+
+// secretFileName 加密凭据文件名
+const secretFileName = "secrets.json"
+
+// ErrSecretNotFound 凭据不存在
+var ErrSecretNotFound = errors.New("凭据不存在")
+
+// ErrSecretUnreadable 凭据存在但无法解密，口令或设备变更、凭据被篡改时返回
+var ErrSecretUnreadable = errors.New("凭据无法解密")
+
+// SecretStore 凭据存储
+type SecretStore interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+var (
+	secretStoreOnce sync.Once
+	secretStoreImpl SecretStore
+)
+
+// secretStore 返回当前平台可用的凭据存储，优先使用系统钥匙串
+func secretStore() SecretStore {
+	secretStoreOnce.Do(func() {
+		if os.Getenv(secretBackendEnv) != "file" {
+			// 探测钥匙串是否可用，无桌面环境的 Linux 上通常没有 Secret Service
+			_, err := keyring.Get(secretService, "probe")
+			if err == nil || errors.Is(err, keyring.ErrNotFound) {
+				log.Println("使用系统钥匙串保存凭据")
+				secretStoreImpl = keyringStore{}
+				return
+			}
+			log.Printf("系统钥匙串不可用，改用加密文件保存凭据: %v", err)
+		}
+
+		path := filepath.Join(ConfigDir(), secretFileName)
+		secretStoreImpl = &fileSecretStore{path: path}
+		if os.Getenv(secretPassphraseEnv) == "" {
+			log.Println("警告:", weakSecretWarning)
+		}
+	})
+	return secretStoreImpl
+}
+
+// weakSecretWarning 加密文件没有口令保护时的提示
+const weakSecretWarning = "未设置 " + secretPassphraseEnv + "，加密文件的密钥由本机信息派生，" +
+	"本机任何程序都能解密其中的密码，请设置口令或使用系统钥匙串"
+
+// secretStoreIsWeak 判断凭据是否保存在没有口令保护的加密文件中
+func secretStoreIsWeak() bool {
+	_, isFile := secretStore().(*fileSecretStore)
+	return isFile && os.Getenv(secretPassphraseEnv) == ""
+}
+
+// passwordSecretKey 账号密码在凭据存储中的键名
+func passwordSecretKey(account string) string {
+	return "password/" + account
+}
+
+// keyringStore 基于系统钥匙串的凭据存储
+// (Windows 凭据管理器、macOS 钥匙串、Linux Secret Service)
+type keyringStore struct{}
+
+func (keyringStore) Get(key string) (string, error) {
+	value, err := keyring.Get(secretService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrSecretNotFound
+	}
+	return value, err
+}
+
+func (keyringStore) Set(key, value string) error {
+	return keyring.Set(secretService, key, value)
+}
+
+func (keyringStore) Delete(key string) error {
+	err := keyring.Delete(secretService, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// fileSecretStore 加密文件凭据存储，密钥由用户口令或本机信息经 scrypt 派生。
+// 未设置口令时派生密钥所用的主机名、主目录与 machine-id 对本机任何程序都是公开的，
+// 此时加密只能防止密码被直接看到，不能防止本机其他程序读取
+type fileSecretStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// secretFile 加密文件的结构，每个值单独使用 AES-GCM 加密
+type secretFile struct {
+	Salt    string            `json:"salt"`
+	Secrets map[string]string `json:"secrets"`
+}
+
+func (s *fileSecretStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	sealed, ok := file.Secrets[key]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+
+	aead, err := s.cipher(file.Salt)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", fmt.Errorf("%w: 凭据文件已损坏", ErrSecretUnreadable)
+	}
+
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("%w，口令或设备可能已变更: %v", ErrSecretUnreadable, err)
+	}
+
+	return string(plain), nil
+}
+
+func (s *fileSecretStore) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	aead, err := s.cipher(file.Salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(key))
+	file.Secrets[key] = base64.StdEncoding.EncodeToString(sealed)
+	return s.save(file)
+}
+
+func (s *fileSecretStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := file.Secrets[key]; !ok {
+		return nil
+	}
+	delete(file.Secrets, key)
+	return s.save(file)
+}
+
+// load 读取凭据文件，不存在时生成新的盐值
+func (s *fileSecretStore) load() (*secretFile, error) {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("生成盐值失败: %w", err)
+		}
+		return &secretFile{
+			Salt:    base64.StdEncoding.EncodeToString(salt),
+			Secrets: make(map[string]string),
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取凭据文件失败: %w", err)
+	}
+
+	var file secretFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("解析凭据文件失败: %w", err)
+	}
+	if file.Secrets == nil {
+		file.Secrets = make(map[string]string)
+	}
+	return &file, nil
+}
+
+// save 写入凭据文件，仅当前用户可读写
+func (s *fileSecretStore) save(file *secretFile) error {
+	content, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, content, 0o600)
+}
+
+// cipher 根据盐值派生密钥并创建 AES-GCM 加密器
+func (s *fileSecretStore) cipher(encodedSalt string) (cipher.AEAD, error) {
+	salt, err := base64.StdEncoding.DecodeString(encodedSalt)
+	if err != nil {
+		return nil, fmt.Errorf("凭据文件盐值无效: %w", err)
+	}
+
+	key, err := scrypt.Key([]byte(secretPassphrase()), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// secretPassphrase 返回用户口令；未设置时使用与本机绑定的信息，这些信息本机程序都能读到，见 weakSecretWarning
+func secretPassphrase() string {
+	if passphrase := os.Getenv(secretPassphraseEnv); passphrase != "" {
+		return passphrase
+	}
+
+	parts := []string{secretService}
+	if hostname, err := os.Hostname(); err == nil {
+		parts = append(parts, hostname)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		parts = append(parts, home)
+	}
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if id, err := os.ReadFile(path); err == nil {
+			parts = append(parts, strings.TrimSpace(string(id)))
+			break
+		}
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
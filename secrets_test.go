@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// newTestSecretStore 创建位于临时目录的加密文件凭据存储
+func newTestSecretStore(t *testing.T) *fileSecretStore {
+	t.Helper()
+	return &fileSecretStore{path: filepath.Join(t.TempDir(), secretFileName)}
+}
+
+func TestFileSecretStore(t *testing.T) {
+	store := newTestSecretStore(t)
+
+	if _, err := store.Get("password/201900001"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("凭据文件不存在时期望 %v，实际为 %v", ErrSecretNotFound, err)
+	}
+
+	if err := store.Set("password/201900001", "secret-password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("password/201900002", ""); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := store.Get("password/201900001"); err != nil || value != "secret-password" {
+		t.Errorf("读取的凭据不正确: %q %v", value, err)
+	}
+	if value, err := store.Get("password/201900002"); err != nil || value != "" {
+		t.Errorf("空密码读取后不正确: %q %v", value, err)
+	}
+
+	content, err := os.ReadFile(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "secret-password") {
+		t.Errorf("凭据文件中出现明文密码: %s", content)
+	}
+	if info, err := os.Stat(store.path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		t.Errorf("凭据文件权限过宽: %v", info.Mode().Perm())
+	}
+
+	if err := store.Delete("password/201900001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("password/201900001"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("删除后期望 %v，实际为 %v", ErrSecretNotFound, err)
+	}
+	if err := store.Delete("password/201900001"); err != nil {
+		t.Errorf("删除不存在的凭据不应出错: %v", err)
+	}
+}
+
+func TestFileSecretStoreWrongPassphrase(t *testing.T) {
+	store := newTestSecretStore(t)
+	if err := store.Set("password/201900001", "secret-password"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(secretPassphraseEnv, "another-passphrase")
+	value, err := store.Get("password/201900001")
+	if !errors.Is(err, ErrSecretUnreadable) {
+		t.Errorf("口令变更后应无法解密，实际为 %q %v", value, err)
+	}
+}
+
+func TestLoadConfigWithUnreadablePassword(t *testing.T) {
+	config := DefaultConfig()
+	config.Countindex = "201900006"
+	config.Passwordindex = "secret-password"
+	if err := SaveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	// 口令变更后密码无法解密，配置仍能读取，密码留空等待重新输入
+	t.Setenv(secretPassphraseEnv, "another-passphrase")
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("密码无法解密时读取配置失败: %v", err)
+	}
+	if loaded.Countindex != "201900006" || loaded.Passwordindex != "" {
+		t.Errorf("读取的配置不正确: %q %q", loaded.Countindex, loaded.Passwordindex)
+	}
+
+	// 修改其他配置项后保存，不能用空密码覆盖无法解密的密码
+	loaded.Driver = "rod"
+	if err := SaveConfig(loaded); err != nil {
+		t.Fatalf("保存配置失败: %v", err)
+	}
+	t.Setenv(secretPassphraseEnv, "test-passphrase")
+	if password, err := secretStore().Get(passwordSecretKey("201900006")); err != nil || password != "secret-password" {
+		t.Errorf("保存配置后原来的密码被覆盖: %q %v", password, err)
+	}
+}
+
+func TestFileSecretStoreCorrupted(t *testing.T) {
+	store := newTestSecretStore(t)
+	if err := store.Set("password/201900001", "secret-password"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("password/201900002", "other-password"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(store.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rewrite 修改凭据文件中的加密值后写回
+	rewrite := func(t *testing.T, modify func(secrets map[string]string)) {
+		t.Helper()
+		var file secretFile
+		if err := json.Unmarshal(content, &file); err != nil {
+			t.Fatal(err)
+		}
+		modify(file.Secrets)
+		if err := store.save(&file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(t *testing.T)
+	}{
+		{"文件被截断", func(t *testing.T) {
+			if err := os.WriteFile(store.path, content[:len(content)/2], 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"加密值被截断", func(t *testing.T) {
+			rewrite(t, func(secrets map[string]string) { secrets["password/201900001"] = "AAAA" })
+		}},
+		{"加密值不是 base64", func(t *testing.T) {
+			rewrite(t, func(secrets map[string]string) { secrets["password/201900001"] = "not base64!" })
+		}},
+		{"加密值被篡改", func(t *testing.T) {
+			rewrite(t, func(secrets map[string]string) {
+				sealed := []byte(secrets["password/201900001"])
+				sealed[len(sealed)/2] ^= 1
+				secrets["password/201900001"] = string(sealed)
+			})
+		}},
+		{"加密值被换到其他账号", func(t *testing.T) {
+			rewrite(t, func(secrets map[string]string) { secrets["password/201900001"] = secrets["password/201900002"] })
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.modify(t)
+			value, err := store.Get("password/201900001")
+			if err == nil || errors.Is(err, ErrSecretNotFound) {
+				t.Errorf("损坏的凭据文件应返回错误，实际为 %q %v", value, err)
+			}
+		})
+	}
+
+	// 无法解析的凭据文件不能被新的凭据覆盖
+	if err := os.WriteFile(store.path, content[:len(content)/2], 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("password/201900003", "new-password"); err == nil {
+		t.Error("凭据文件无法解析时不应覆盖")
+	}
+}
+
+func TestLoadConfigMigratesPlaintextPassword(t *testing.T) {
+	// 设置了 file 时不使用系统钥匙串
+	if _, ok := secretStore().(*fileSecretStore); !ok {
+		t.Fatalf("%s=file 时应使用加密文件存储，实际为 %T", secretBackendEnv, secretStore())
+	}
+
+	v4 := `{"version":4,"default_profile":"默认","driver":"http","profiles":[` +
+		`{"name":"默认","webindex":"http://10.0.0.1/eportal/index.jsp?wlanuserip=10.0.0.2",` +
+		`"countindex":"201900004","passwordindex":"plain-password","operatorindex":"移动"},` +
+		`{"name":"备用","webindex":"http://10.0.0.1/eportal/index.jsp?wlanuserip=10.0.0.2",` +
+		`"countindex":"201900005","passwordindex":"backup-password","operatorindex":"电信"}]}`
+	if err := os.WriteFile(configFilePath(), []byte(v4), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("读取配置失败: %v", err)
+	}
+	if config.Passwordindex != "plain-password" {
+		t.Errorf("默认账号的密码为 %q", config.Passwordindex)
+	}
+
+	content, err := os.ReadFile(configFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "plain-password") || strings.Contains(string(content), "backup-password") {
+		t.Errorf("迁移后配置文件中仍有明文密码: %s", content)
+	}
+	for account, want := range map[string]string{"201900004": "plain-password", "201900005": "backup-password"} {
+		if got, err := secretStore().Get(passwordSecretKey(account)); err != nil || got != want {
+			t.Errorf("账号 %s 的密码没有迁移到凭据存储: %q %v", account, got, err)
+		}
+	}
+}
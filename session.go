@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// SessionDetail 认证系统返回的一项附加信息，例如已用流量、在线时长、账户余额
+type SessionDetail struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SessionInfo 当前在线用户的会话信息
+type SessionInfo struct {
+	Online      bool            `json:"online"`
+	UserName    string          `json:"user_name"`
+	UserID      string          `json:"user_id"`
+	IP          string          `json:"ip"`
+	MAC         string          `json:"mac"`
+	Service     string          `json:"service"`
+	OnlineTime  string          `json:"online_time"`
+	UsedTraffic string          `json:"used_traffic"`
+	Balance     string          `json:"balance"`
+	Details     []SessionDetail `json:"details"`
+}
+
+// sessionDetailKeywords 附加信息名称或编号中的关键词与 SessionInfo 字段的对应关系
+var sessionDetailKeywords = []struct {
+	keywords []string
+	field    func(info *SessionInfo) *string
+}{
+	{[]string{"余额", "balance", "fee"}, func(info *SessionInfo) *string { return &info.Balance }},
+	{[]string{"流量", "flow", "traffic"}, func(info *SessionInfo) *string { return &info.UsedTraffic }},
+	{[]string{"时长", "time"}, func(info *SessionInfo) *string { return &info.OnlineTime }},
+}
+
+// GetSessionInfo 通过 getOnlineUserInfo 接口查询当前在线用户，未登录时返回 Online 为 false 的结果
+func (a *App) GetSessionInfo() (*SessionInfo, error) {
+	ctx, done := a.track(OperationSession)
+	defer done()
+
+	config, err := LoadConfigOrDefault()
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Webindex == "" {
+		return nil, fmt.Errorf("未配置登录页面地址")
+	}
+
+	client, err := NewEPortalClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := client.OnlineUserInfo(ctx, rememberedUserIndex())
+	if errors.Is(err, ErrNotOnline) {
+		return &SessionInfo{Details: []SessionDetail{}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询在线信息失败: %w", err)
+	}
+
+	return parseSessionInfo(info), nil
+}
+
+// parseSessionInfo 将 getOnlineUserInfo 的返回结果整理为 SessionInfo
+func parseSessionInfo(info *ePortalUserInfo) *SessionInfo {
+	session := &SessionInfo{
+		Online:   true,
+		UserName: info.UserName,
+		UserID:   info.UserID,
+		IP:       info.UserIP,
+		MAC:      info.UserMAC,
+		Service:  info.Service,
+		Balance:  info.AccountFee,
+		Details:  []SessionDetail{},
+	}
+
+	// ballInfo 是以字符串形式嵌套的 JSON 数组，各学校显示的项目不同
+	if info.BallInfo != "" {
+		var items []struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"displayName"`
+			Value       string `json:"value"`
+		}
+		if err := json.Unmarshal([]byte(info.BallInfo), &items); err != nil {
+			log.Printf("解析 ballInfo 失败: %v", err)
+		}
+
+		for _, item := range items {
+			name := item.DisplayName
+			if name == "" {
+				name = item.ID
+			}
+			session.Details = append(session.Details, SessionDetail{Name: name, Value: item.Value})
+
+			key := strings.ToLower(item.ID + " " + item.DisplayName)
+			for _, mapping := range sessionDetailKeywords {
+				if !containsAny(key, mapping.keywords) {
+					continue
+				}
+				if field := mapping.field(session); *field == "" {
+					*field = item.Value
+				}
+				break
+			}
+		}
+	}
+
+	return session
+}
+
+// containsAny 判断字符串是否包含任意一个关键词
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// 深澜认证协议中的固定参数
+const (
+	srunEncVer      = "srun_bx1"
+	srunN           = "200"
+	srunType        = "1"
+	srunDefaultACID = "1"
+	// srunAlphabet 深澜使用的自定义 base64 字母表
+	srunAlphabet = "LVoJPiCN2R8G90yg+hmFHuacZ1OWMnrsSTXkYpUq/3dlbfKwv6xztjI7DeBE45QA"
+)
+
+var srunEncoding = base64.NewEncoding(srunAlphabet)
+
+// SrunClient 深澜 Srun 认证协议客户端。登录需要先获取 challenge，
+// 再用其加密账号信息并计算校验和，无法通过点击页面完成
+type SrunClient struct {
+	baseURL    *url.URL
+	acid       string
+	httpClient *http.Client
+}
+
+// srunResponse 深澜接口返回的 JSON 结构，error 为 ok 表示成功
+type srunResponse struct {
+	Error     string `json:"error"`
+	ErrorMsg  string `json:"error_msg"`
+	Res       string `json:"res"`
+	SucMsg    string `json:"suc_msg"`
+	Challenge string `json:"challenge"`
+	ClientIP  string `json:"client_ip"`
+	OnlineIP  string `json:"online_ip"`
+}
+
+// srunUserInfo rad_user_info 接口返回的在线信息
+type srunUserInfo struct {
+	Error       string  `json:"error"`
+	UserName    string  `json:"user_name"`
+	OnlineIP    string  `json:"online_ip"`
+	UserMAC     string  `json:"user_mac"`
+	SumBytes    float64 `json:"sum_bytes"`
+	SumSeconds  float64 `json:"sum_seconds"`
+	UserBalance float64 `json:"user_balance"`
+}
+
+// message 返回接口的提示文字
+func (r *srunResponse) message() string {
+	for _, msg := range []string{r.ErrorMsg, r.SucMsg, r.Res, r.Error} {
+		if msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+// NewSrunClient 根据认证页面地址创建客户端，ac_id 取自地址参数
+func NewSrunClient(portalURL string, timeout time.Duration) (*SrunClient, error) {
+	u, err := url.Parse(portalURL)
+	if err != nil {
+		return nil, fmt.Errorf("解析登录链接失败: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("登录链接不完整: %s", portalURL)
+	}
+
+	acid := u.Query().Get("ac_id")
+	if acid == "" {
+		acid = srunDefaultACID
+	}
+
+	return &SrunClient{
+		baseURL: &url.URL{Scheme: u.Scheme, Host: u.Host},
+		acid:    acid,
+		httpClient: &http.Client{
+			Timeout:   timeout,
+			Transport: portalTransport,
+		},
+	}, nil
+}
+
+// apiURL 拼接接口地址
+func (c *SrunClient) apiURL(path string) string {
+	return c.baseURL.ResolveReference(&url.URL{Path: path}).String()
+}
+
+// get 以 JSONP 方式调用接口并将结果解析到 v，与页面脚本的请求方式保持一致
+func (c *SrunClient) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	callback := "jQuery" + now
+	params.Set("callback", callback)
+	params.Set("_", now)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.apiURL(path)+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求 %s 失败: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("请求 %s 返回状态码 %d", path, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if err := json.Unmarshal(unwrapJSONP(body), v); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	return nil
+}
+
+// unwrapJSONP 去掉 JSONP 的回调函数名与括号，普通 JSON 原样返回
+func unwrapJSONP(body []byte) []byte {
+	body = bytes.TrimSpace(body)
+	start := bytes.IndexByte(body, '(')
+	end := bytes.LastIndexByte(body, ')')
+	if len(body) > 0 && body[0] != '{' && start >= 0 && end > start {
+		return body[start+1 : end]
+	}
+	return body
+}
+
+// Challenge 获取本次登录使用的 challenge，同时返回认证系统看到的本机地址
+func (c *SrunClient) Challenge(ctx context.Context, username, ip string) (*srunResponse, error) {
+	params := url.Values{}
+	params.Set("username", username)
+	params.Set("ip", ip)
+
+	var result srunResponse
+	if err := c.get(ctx, "/cgi-bin/get_challenge", params, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "ok" || result.Challenge == "" {
+		return &result, fmt.Errorf("获取 challenge 失败: %s", result.message())
+	}
+	return &result, nil
+}
+
+// Login 获取 challenge 后提交加密的账号信息完成认证
+func (c *SrunClient) Login(ctx context.Context, username, password string) (*srunResponse, error) {
+	challenge, err := c.Challenge(ctx, username, "")
+	if err != nil {
+		return challenge, err
+	}
+	ip := challenge.ClientIP
+	if ip == "" {
+		ip = challenge.OnlineIP
+	}
+
+	params := srunLoginParams(username, password, ip, c.acid, challenge.Challenge)
+	var result srunResponse
+	if err := c.get(ctx, "/cgi-bin/srun_portal", params, &result); err != nil {
+		return nil, err
+	}
+	if result.ClientIP == "" {
+		result.ClientIP = ip
+	}
+	if result.Error != "ok" {
+		return &result, portalFailure(result.message())
+	}
+	return &result, nil
+}
+
+// UserInfo 查询本机的在线信息，未登录时返回 ErrNotOnline
+func (c *SrunClient) UserInfo(ctx context.Context) (*srunUserInfo, error) {
+	var result srunUserInfo
+	if err := c.get(ctx, "/cgi-bin/rad_user_info", url.Values{}, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "ok" {
+		return nil, ErrNotOnline
+	}
+	return &result, nil
+}
+
+// Logout 注销在线用户
+func (c *SrunClient) Logout(ctx context.Context, username, ip string) (*srunResponse, error) {
+	params := url.Values{}
+	params.Set("action", "logout")
+	params.Set("username", username)
+	params.Set("ip", ip)
+	params.Set("ac_id", c.acid)
+
+	var result srunResponse
+	if err := c.get(ctx, "/cgi-bin/srun_portal", params, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "ok" {
+		if strings.Contains(strings.ToLower(result.message()), "not online") {
+			return &result, ErrNotOnline
+		}
+		return &result, fmt.Errorf("注销失败: %s", result.message())
+	}
+	return &result, nil
+}
+
+// srunLoginParams 按深澜页面脚本的算法生成登录参数：
+// 密码为以 challenge 为密钥的 HMAC-MD5，账号信息经 xEncode 加密后用自定义 base64 编码，
+// chksum 为 challenge 与各参数交替拼接后的 SHA1
+func srunLoginParams(username, password, ip, acid, token string) url.Values {
+	hmd5 := srunHMACMD5(password, token)
+	info := srunInfo(username, password, ip, acid, token)
+	chksum := srunChecksum(token, username, hmd5, acid, ip, info)
+
+	params := url.Values{}
+	params.Set("action", "login")
+	params.Set("username", username)
+	params.Set("password", "{MD5}"+hmd5)
+	params.Set("os", "Windows 10")
+	params.Set("name", "Windows")
+	params.Set("double_stack", "0")
+	params.Set("chksum", chksum)
+	params.Set("info", info)
+	params.Set("ac_id", acid)
+	params.Set("ip", ip)
+	params.Set("n", srunN)
+	params.Set("type", srunType)
+	return params
+}
+
+// srunHMACMD5 计算以 challenge 为密钥的密码 HMAC-MD5
+func srunHMACMD5(password, token string) string {
+	mac := hmac.New(md5.New, []byte(token))
+	mac.Write([]byte(password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// srunInfo 生成 {SRBX1} 开头的加密账号信息
+func srunInfo(username, password, ip, acid, token string) string {
+	// 字段顺序与页面脚本中的 JSON.stringify 一致，且不转义 HTML 字符
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		IP       string `json:"ip"`
+		ACID     string `json:"acid"`
+		EncVer   string `json:"enc_ver"`
+	}{username, password, ip, acid, srunEncVer})
+
+	data := strings.TrimSuffix(buf.String(), "\n")
+	return "{SRBX1}" + srunEncoding.EncodeToString(srunXEncode(data, token))
+}
+
+// srunChecksum 计算登录请求的 chksum
+func srunChecksum(token, username, hmd5, acid, ip, info string) string {
+	var sb strings.Builder
+	for _, part := range []string{username, hmd5, acid, ip, srunN, srunType, info} {
+		sb.WriteString(token)
+		sb.WriteString(part)
+	}
+	sum := sha1.Sum([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// srunPack 将字符串按小端每 4 个字符打包为一个整数，与页面脚本一样按 UTF-16 编码单元计算，
+// withLength 为 true 时在末尾追加字符串长度
+func srunPack(s string, withLength bool) []uint32 {
+	units := utf16.Encode([]rune(s))
+	v := make([]uint32, 0, (len(units)+3)/4+1)
+	for i := 0; i < len(units); i += 4 {
+		var word uint32
+		for j := 0; j < 4 && i+j < len(units); j++ {
+			word |= uint32(units[i+j]) << (8 * j)
+		}
+		v = append(v, word)
+	}
+	if withLength {
+		v = append(v, uint32(len(units)))
+	}
+	return v
+}
+
+// srunUnpack 将整数数组按小端展开为字节
+func srunUnpack(v []uint32) []byte {
+	out := make([]byte, 0, len(v)*4)
+	for _, word := range v {
+		out = append(out, byte(word), byte(word>>8), byte(word>>16), byte(word>>24))
+	}
+	return out
+}
+
+// srunXEncode 深澜页面脚本中的 xEncode，为 XXTEA 的变体
+func srunXEncode(msg, key string) []byte {
+	if msg == "" {
+		return nil
+	}
+
+	v := srunPack(msg, true)
+	k := srunPack(key, false)
+	for len(k) < 4 {
+		k = append(k, 0)
+	}
+
+	n := len(v) - 1
+	z := v[n]
+	var y, m, e, d uint32
+	const delta = 0x9E3779B9
+	for q := 6 + 52/(n+1); q > 0; q-- {
+		d += delta
+		e = d >> 2 & 3
+		p := 0
+		for ; p < n; p++ {
+			y = v[p+1]
+			m = z>>5 ^ y<<2
+			m += (y>>3 ^ z<<4) ^ (d ^ y)
+			m += k[uint32(p&3)^e] ^ z
+			v[p] += m
+			z = v[p]
+		}
+		y = v[0]
+		m = z>>5 ^ y<<2
+		m += (y>>3 ^ z<<4) ^ (d ^ y)
+		m += k[uint32(p&3)^e] ^ z
+		v[n] += m
+		z = v[n]
+	}
+	return srunUnpack(v)
+}
+
+// srunDriver 通过深澜认证协议登录的驱动
+type srunDriver struct{}
+
+func init() {
+	RegisterLoginDriver("srun", func() LoginDriver { return &srunDriver{} })
+}
+
+func (d *srunDriver) Login(ctx context.Context, config *Config) (*LoginResult, error) {
+	log.Println("通过深澜认证协议登录...")
+	start := time.Now()
+	result := newLoginResult("srun", config)
+
+	client, err := NewSrunClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	var response *srunResponse
+	err = result.runStep(ctx, "提交认证", func() (err error) {
+		response, err = client.Login(ctx, config.Countindex, config.Passwordindex)
+		return err
+	})
+	result.FinalURL = client.apiURL("/cgi-bin/srun_portal")
+	if response != nil {
+		result.Message = response.message()
+		if response.ClientIP != "" {
+			result.IP = response.ClientIP
+		}
+	}
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	result.Outcome = LoginOutcomeSuccess
+	log.Printf("深澜认证成功，IP: %s", result.IP)
+	return result, result.finish(start, nil)
+}
+
+func (d *srunDriver) Probe(ctx context.Context, config *Config) (string, error) {
+	client, err := NewSrunClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	challenge, err := client.Challenge(ctx, config.Countindex, "")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("连接测试结果:\n- 认证系统: 深澜 Srun\n- challenge: 获取成功\n- 本机地址: %s", challenge.ClientIP), nil
+}
+
+func (d *srunDriver) Logout(ctx context.Context, config *Config) (*LoginResult, error) {
+	log.Println("通过深澜认证协议注销...")
+	start := time.Now()
+	result := newLoginResult("srun", config)
+
+	client, err := NewSrunClient(config.Webindex, 10*time.Second)
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	var info *srunUserInfo
+	err = result.runStep(ctx, "查询在线用户", func() (err error) {
+		info, err = client.UserInfo(ctx)
+		return err
+	})
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+	result.IP = info.OnlineIP
+
+	username := info.UserName
+	if username == "" {
+		username = config.Countindex
+	}
+
+	var response *srunResponse
+	err = result.runStep(ctx, "提交注销", func() (err error) {
+		response, err = client.Logout(ctx, username, info.OnlineIP)
+		return err
+	})
+	result.FinalURL = client.apiURL("/cgi-bin/srun_portal")
+	if response != nil {
+		result.Message = response.message()
+	}
+	if err != nil {
+		return result, result.finish(start, err)
+	}
+
+	result.Outcome = LoginOutcomeSuccess
+	return result, result.finish(start, nil)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// 登录步骤的状态
+const (
+	StepStarted   = "started"
+	StepRetrying  = "retrying"
+	StepSucceeded = "succeeded"
+	StepFailed    = "failed"
+)
+
+// operationStepEvent 步骤状态变化时发送给前端的事件
+const operationStepEvent = "operation:step"
+
+// StepEvent ExecuteLoginStep 报告的步骤进度
+type StepEvent struct {
+	// Operation 步骤所属的操作 ID
+	Operation string `json:"operation,omitempty"`
+	Step      string `json:"step"`
+	Status    string `json:"status"`
+	// Attempt 当前是第几次尝试
+	Attempt int `json:"attempt"`
+	// ElapsedMs 步骤开始以来的耗时
+	ElapsedMs int64 `json:"elapsed_ms"`
+	// Error 结束或重试时为上一次尝试的错误
+	Error string `json:"error,omitempty"`
+}
+
+// stepListenerKey 在 context 中保存步骤监听器的键
+type stepListenerKey struct{}
+
+// WithStepListener 返回携带步骤监听器的 context，ExecuteLoginStep 通过它报告步骤进度。
+// 监听器在执行步骤的协程中同步调用，不应阻塞
+func WithStepListener(ctx context.Context, listener func(StepEvent)) context.Context {
+	return context.WithValue(ctx, stepListenerKey{}, listener)
+}
+
+// publishStep 将步骤事件交给 context 中的监听器，没有监听器时忽略
+func publishStep(ctx context.Context, event StepEvent) {
+	if listener, ok := ctx.Value(stepListenerKey{}).(func(StepEvent)); ok {
+		listener(event)
+	}
+}
+
+// stepSubscribers 订阅所有操作步骤事件的监听器，命令行模式用它显示进度
+type stepSubscribers struct {
+	mu        sync.Mutex
+	next      int
+	listeners map[int]func(StepEvent)
+}
+
+// subscribe 添加监听器，返回取消订阅的函数
+func (s *stepSubscribers) subscribe(listener func(StepEvent)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listeners == nil {
+		s.listeners = map[int]func(StepEvent){}
+	}
+	s.next++
+	id := s.next
+	s.listeners[id] = listener
+	return func() {
+		s.mu.Lock()
+		delete(s.listeners, id)
+		s.mu.Unlock()
+	}
+}
+
+// publish 将事件交给所有监听器
+func (s *stepSubscribers) publish(event StepEvent) {
+	s.mu.Lock()
+	listeners := make([]func(StepEvent), 0, len(s.listeners))
+	for _, listener := range s.listeners {
+		listeners = append(listeners, listener)
+	}
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// subscribeSteps 订阅所有操作的步骤事件，返回取消订阅的函数
+func (a *App) subscribeSteps(listener func(StepEvent)) func() {
+	return a.steps.subscribe(listener)
+}
+
+// withStepEvents 让操作的步骤事件发送给前端与订阅者
+func (a *App) withStepEvents(ctx context.Context, operation string) context.Context {
+	return WithStepListener(ctx, func(event StepEvent) {
+		event.Operation = operation
+		a.emit(operationStepEvent, event)
+		a.steps.publish(event)
+	})
+}
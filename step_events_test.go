@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestStepEvents(t *testing.T) {
+	// 没有监听器时忽略事件
+	publishStep(context.Background(), StepEvent{Step: "填写用户名", Status: StepStarted})
+
+	app := &App{}
+	var received []StepEvent
+	unsubscribe := app.subscribeSteps(func(event StepEvent) {
+		received = append(received, event)
+	})
+
+	ctx := app.withStepEvents(context.Background(), "login-1")
+	publishStep(ctx, StepEvent{Step: "填写用户名", Status: StepStarted, Attempt: 1})
+	publishStep(ctx, StepEvent{Step: "填写用户名", Status: StepRetrying, Attempt: 2, Error: "元素不存在"})
+	if len(received) != 2 || received[0].Operation != "login-1" || received[1].Status != StepRetrying {
+		t.Errorf("订阅者收到的事件不正确: %+v", received)
+	}
+
+	// 取消订阅后不再收到事件
+	unsubscribe()
+	publishStep(ctx, StepEvent{Step: "填写用户名", Status: StepSucceeded, Attempt: 2})
+	if len(received) != 2 {
+		t.Errorf("取消订阅后仍收到事件: %+v", received)
+	}
+}
+
+func TestHTTPDriverStepEvents(t *testing.T) {
+	portal := newMockPortal(t, scenarioSuccess)
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"登录成功", "correct-password", []string{"提交认证:" + StepStarted, "提交认证:" + StepSucceeded}},
+		{"密码错误", "wrong-password", []string{"提交认证:" + StepStarted, "提交认证:" + StepFailed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := saveTestConfig(t, portal, "http", tt.password)
+			var got []string
+			ctx := WithStepListener(context.Background(), func(event StepEvent) {
+				got = append(got, event.Step+":"+event.Status)
+			})
+
+			result, _ := loginWithHTTP(ctx, config)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("期望步骤事件 %v，实际为 %v", tt.want, got)
+			}
+			if len(result.Steps) != 1 || result.Steps[0].Name != "提交认证" {
+				t.Errorf("登录结果中的步骤不正确: %+v", result.Steps)
+			}
+		})
+	}
+}
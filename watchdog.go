@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// watchdogInterval 网络正常时的检测间隔
+	watchdogInterval = 60 * time.Second
+	// watchdogMinInterval 检测间隔的下限，避免过短的间隔导致频繁请求
+	watchdogMinInterval = 5 * time.Second
+	// watchdogMaxBackoff 登录连续失败时的最大检测间隔
+	watchdogMaxBackoff = 10 * time.Minute
+	// watchdogProbeTimeout 单次连通性检测的超时时间
+	watchdogProbeTimeout = 10 * time.Second
+)
+
+// errWatchdogOffline 网络不通且没有被重定向到认证页面
+var errWatchdogOffline = errors.New("网络未连接，且未被重定向到认证页面")
+
+// Watchdog 后台网络守护，检测到掉线被重定向到认证页面时自动重新登录
+type Watchdog struct {
+	interval   time.Duration
+	maxBackoff time.Duration
+	login      func(ctx context.Context) (*LoginResult, error)
+	paused     atomic.Bool
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWatchdog 创建网络守护，login 为掉线后执行的登录流程，停止守护时 ctx 被取消。
+// 小于 watchdogMinInterval 的间隔按下限处理
+func NewWatchdog(interval time.Duration, login func(ctx context.Context) (*LoginResult, error)) *Watchdog {
+	if interval < watchdogMinInterval {
+		interval = watchdogMinInterval
+	}
+	return &Watchdog{
+		interval:   interval,
+		maxBackoff: watchdogMaxBackoff,
+		login:      login,
+	}
+}
+
+// Start 启动后台检测，重复调用不会启动多个协程
+func (w *Watchdog) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stop != nil {
+		return
+	}
+
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	go w.run(w.stop, w.done)
+	log.Printf("网络守护已启动，检测间隔: %v", w.interval)
+}
+
+// Stop 停止后台检测并等待协程退出
+func (w *Watchdog) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+	log.Println("网络守护已停止")
+}
+
+// Pause 暂停自动重新登录，用户主动注销后调用
+func (w *Watchdog) Pause() {
+	if !w.paused.Swap(true) {
+		log.Println("网络守护已暂停，下次登录成功后恢复")
+	}
+}
+
+// Resume 恢复自动重新登录
+func (w *Watchdog) Resume() {
+	if w.paused.Swap(false) {
+		log.Println("网络守护已恢复")
+	}
+}
+
+// run 检测循环，失败时指数退避
+func (w *Watchdog) run(stop, done chan struct{}) {
+	defer close(done)
+
+	// 停止时中止进行中的检测
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	delay := w.interval
+	for {
+		timer := time.NewTimer(withJitter(delay))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := w.check(ctx)
+		delay = w.nextDelay(delay, err)
+		if err != nil {
+			log.Printf("网络守护将在 %v 后重试", delay)
+		}
+	}
+}
+
+// nextDelay 计算下次检测的间隔：成功时恢复正常间隔，失败时指数退避
+func (w *Watchdog) nextDelay(delay time.Duration, err error) time.Duration {
+	if err == nil {
+		return w.interval
+	}
+
+	delay *= 2
+	if delay > w.maxBackoff || isPermanentLoginError(err) {
+		// 密码错误、账号欠费等需要用户处理，直接按最大间隔重试
+		delay = w.maxBackoff
+	}
+	return delay
+}
+
+// check 执行一次检测，网络正常或重新登录成功时返回 nil
+func (w *Watchdog) check(ctx context.Context) error {
+	if w.paused.Load() {
+		return nil
+	}
+
+	config, err := LoadConfig()
+	if err != nil || !config.Watchdog || config.Countindex == "" {
+		return nil
+	}
+
+	connected, portalURL, err := CheckConnectivity(ctx, watchdogProbeTimeout)
+	if err != nil {
+		log.Printf("网络守护检测失败: %v", err)
+		return err
+	}
+	if connected {
+		return nil
+	}
+	if portalURL == "" {
+		log.Println("网络未连接，且未被重定向到认证页面")
+		return errWatchdogOffline
+	}
+
+	log.Printf("检测到认证页面 %s，开始重新登录", portalURL)
+	result, err := w.login(ctx)
+	if errors.Is(err, ErrAlreadyOnline) {
+		log.Println("认证页面提示账号已在线，跳过重新登录")
+		return nil
+	}
+	if err != nil {
+		log.Printf("网络守护重新登录失败: %v", err)
+		return err
+	}
+
+	log.Printf("网络守护重新登录成功，耗时 %dms", result.DurationMs)
+	return nil
+}
+
+// withJitter 在间隔上叠加 ±20% 的随机抖动，避免多台设备同时请求
+func withJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	jitter := time.Duration(rand.Int63n(int64(d)/5*2+1)) - d/5
+	return d + jitter
+}
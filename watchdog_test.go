@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWatchdogNextDelay(t *testing.T) {
+	w := NewWatchdog(time.Minute, nil)
+	tests := []struct {
+		name  string
+		delay time.Duration
+		err   error
+		want  time.Duration
+	}{
+		{"成功后恢复正常间隔", 8 * time.Minute, nil, time.Minute},
+		{"失败后间隔加倍", 2 * time.Minute, errors.New("网络错误"), 4 * time.Minute},
+		{"不超过最大间隔", 8 * time.Minute, errors.New("网络错误"), watchdogMaxBackoff},
+		{"认证页面拒绝时直接使用最大间隔", time.Minute, &PortalError{Kind: ErrBadCredentials}, watchdogMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := w.nextDelay(tt.delay, tt.err); got != tt.want {
+				t.Errorf("期望 %v，实际为 %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWatchdogIntervalBounds(t *testing.T) {
+	if w := NewWatchdog(0, nil); w.interval != watchdogMinInterval {
+		t.Errorf("间隔为 0 时应使用下限 %v，实际为 %v", watchdogMinInterval, w.interval)
+	}
+	for _, d := range []time.Duration{0, -time.Second} {
+		if got := withJitter(d); got != d {
+			t.Errorf("withJitter(%v) 应原样返回，实际为 %v", d, got)
+		}
+	}
+}
+
+func TestWatchdogCheck(t *testing.T) {
+	saveTestConfig(t, newMockPortal(t, scenarioSuccess), "http", "correct-password")
+	var page atomic.Value
+	setConnectivityProbe(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(page.Load().(string)))
+	})
+
+	var loginErr error
+	logins := 0
+	w := NewWatchdog(time.Minute, func(ctx context.Context) (*LoginResult, error) {
+		logins++
+		return &LoginResult{}, loginErr
+	})
+	ctx := context.Background()
+
+	page.Store("<title>百度一下</title>")
+	if err := w.check(ctx); err != nil || logins != 0 {
+		t.Errorf("网络正常时不应登录: %v, 登录 %d 次", err, logins)
+	}
+
+	page.Store("<html></html>")
+	if err := w.check(ctx); !errors.Is(err, errWatchdogOffline) || logins != 0 {
+		t.Errorf("没有认证页面时期望 %v，实际为 %v, 登录 %d 次", errWatchdogOffline, err, logins)
+	}
+
+	page.Store(gatewayRedirectPage)
+	if err := w.check(ctx); err != nil || logins != 1 {
+		t.Errorf("被劫持到认证页面时应重新登录: %v, 登录 %d 次", err, logins)
+	}
+
+	loginErr = &PortalError{Kind: ErrBadCredentials, Message: "密码错误"}
+	if err := w.check(ctx); !errors.Is(err, ErrBadCredentials) {
+		t.Errorf("期望错误 %v，实际为 %v", ErrBadCredentials, err)
+	}
+
+	// 账号已在线视为成功
+	loginErr = &PortalError{Kind: ErrAlreadyOnline, Message: "用户已在线"}
+	if err := w.check(ctx); err != nil {
+		t.Errorf("账号已在线时不应返回错误: %v", err)
+	}
+
+	w.Pause()
+	if err := w.check(ctx); err != nil || logins != 3 {
+		t.Errorf("暂停时不应登录: %v, 登录 %d 次", err, logins)
+	}
+	w.Resume()
+	if err := w.check(ctx); logins != 4 {
+		t.Errorf("恢复后应重新登录: %v, 登录 %d 次", err, logins)
+	}
+}
+
+func TestWatchdogStartStop(t *testing.T) {
+	saveTestConfig(t, newMockPortal(t, scenarioSuccess), "http", "correct-password")
+	setConnectivityProbe(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(gatewayRedirectPage))
+	})
+
+	logins := make(chan struct{}, 100)
+	w := NewWatchdog(time.Minute, func(ctx context.Context) (*LoginResult, error) {
+		logins <- struct{}{}
+		return &LoginResult{}, nil
+	})
+	w.interval = 20 * time.Millisecond
+	w.Start()
+	w.Start()
+
+	select {
+	case <-logins:
+	case <-time.After(2 * time.Second):
+		t.Fatal("网络守护没有重新登录")
+	}
+
+	w.Stop()
+	w.Stop()
+	for len(logins) > 0 {
+		<-logins
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := len(logins); n != 0 {
+		t.Errorf("停止后仍登录了 %d 次", n)
+	}
+}
+
+func TestWatchdogStopCancelsLogin(t *testing.T) {
+	saveTestConfig(t, newMockPortal(t, scenarioSuccess), "http", "correct-password")
+	setConnectivityProbe(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(gatewayRedirectPage))
+	})
+
+	started := make(chan struct{})
+	var loginErr atomic.Value
+	w := NewWatchdog(time.Minute, func(ctx context.Context) (*LoginResult, error) {
+		close(started)
+		<-ctx.Done()
+		loginErr.Store(ctx.Err())
+		return nil, ctx.Err()
+	})
+	w.interval = 20 * time.Millisecond
+	w.Start()
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("网络守护没有重新登录")
+	}
+
+	// 停止守护时中止进行中的登录，不等待登录完成
+	stopped := make(chan struct{})
+	go func() {
+		w.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("停止网络守护时没有中止进行中的登录")
+	}
+	if err, _ := loginErr.Load().(error); !errors.Is(err, context.Canceled) {
+		t.Errorf("期望登录被取消，实际为 %v", err)
+	}
+}